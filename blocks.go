@@ -0,0 +1,230 @@
+package gtfs2sqlite
+
+import (
+	"cmp"
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Trips sharing a block_id are run by the same vehicle, so are checked on every date they run. Trips in
+// frequencies.txt are skipped, as they stand for many trips.
+
+// blockTrip is a trip in a block, read once however many dates it runs. Times are seconds after the start of the
+// service day, as in GTFS.
+type blockTrip struct {
+	rowid     int64
+	tripID    string
+	serviceID string
+	start     int64
+	end       int64
+	firstStop string
+	lastStop  string
+}
+
+// datedBlockTrip is a blockTrip on one of the dates it runs
+type datedBlockTrip struct {
+	*blockTrip
+	date     int64
+	dayStart int64 // Unix time GTFS times on date are measured from, noon less 12 hours in the agency's timezone
+	start    int64 // Unix time, so trips on different dates compare
+	end      int64
+}
+
+const secondsPerDay = 24 * 60 * 60
+
+// minLayoverSeconds is the least time a vehicle is assumed to need between the end of one trip in a block and the
+// start of the next from somewhere else. Times are usually given to the minute, so only a vehicle that would have to
+// be in two places in the same minute is flagged.
+const minLayoverSeconds = 60
+
+// readBlockTrips reads the trips in each block
+func (v *validator) readBlockTrips() (map[string][]*blockTrip, error) {
+	query := `
+WITH bounds AS (
+	SELECT trip_id, min(stop_sequence) AS first_sequence, max(stop_sequence) AS last_sequence
+	FROM stop_times WHERE typeof(stop_sequence) = 'integer' GROUP BY trip_id
+)
+SELECT * FROM (
+	SELECT trips.rowid AS rowid, trips.trip_id, trips.service_id, trips.block_id,
+		first.stop_id AS __gtfs2sqlite_first_stop,
+		coalesce(first.departure_time, first.arrival_time) AS __gtfs2sqlite_start,
+		last.stop_id AS __gtfs2sqlite_last_stop,
+		coalesce(last.arrival_time, last.departure_time) AS __gtfs2sqlite_end
+	FROM trips
+	JOIN bounds ON bounds.trip_id = trips.trip_id
+	JOIN stop_times AS first ON first.trip_id = trips.trip_id AND first.stop_sequence = bounds.first_sequence
+	JOIN stop_times AS last ON last.trip_id = trips.trip_id AND last.stop_sequence = bounds.last_sequence
+	WHERE trips.block_id IS NOT NULL
+		AND trips.trip_id NOT IN (SELECT trip_id FROM frequencies WHERE trip_id IS NOT NULL)
+)
+WHERE typeof(__gtfs2sqlite_start) = 'integer' AND typeof(__gtfs2sqlite_end) = 'integer'
+ORDER BY rowid`
+
+	blocks := make(map[string][]*blockTrip)
+	err := sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		block := stmt.GetText("block_id")
+		blocks[block] = append(blocks[block], &blockTrip{
+			rowid:     stmt.GetInt64("rowid"),
+			tripID:    stmt.GetText("trip_id"),
+			serviceID: stmt.GetText("service_id"),
+			start:     stmt.GetInt64("__gtfs2sqlite_start"),
+			end:       stmt.GetInt64("__gtfs2sqlite_end"),
+			firstStop: stmt.GetText("__gtfs2sqlite_first_stop"),
+			lastStop:  stmt.GetText("__gtfs2sqlite_last_stop"),
+		})
+		return nil
+	})
+	return blocks, err
+}
+
+// blockDates expands the trips of one block onto every date they run
+type blockDates struct {
+	services map[string]*service
+	loc      *time.Location
+	// Many trips share each service, so their dates are only worked out once
+	dates     map[string][]int64
+	dayStarts map[int64]int64
+}
+
+func (b *blockDates) expand(trips []*blockTrip) []datedBlockTrip {
+	var out []datedBlockTrip
+	for _, trip := range trips {
+		dates, ok := b.dates[trip.serviceID]
+		if !ok {
+			if s := b.services[trip.serviceID]; s != nil {
+				dates, _ = s.dates()
+			}
+			b.dates[trip.serviceID] = dates
+		}
+
+		for _, date := range dates {
+			dayStart, ok := b.dayStarts[date]
+			if !ok {
+				day, _ := parseDateValue(date)
+				noon := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, b.loc)
+				dayStart = noon.Add(-12 * time.Hour).Unix()
+				b.dayStarts[date] = dayStart
+			}
+			out = append(out, datedBlockTrip{
+				blockTrip: trip,
+				date:      date,
+				dayStart:  dayStart,
+				start:     dayStart + trip.start,
+				end:       dayStart + trip.end,
+			})
+		}
+	}
+	return out
+}
+
+// validateBlocks checks the trips in each block don't overlap on any date they run, and that a trip starting at a
+// different stop to where the previous trip ended leaves time to get there. Stops in the same station are treated as
+// the same place. Each pair of trips is reported once, on the later trip.
+func (v *validator) validateBlocks() error {
+	blocks, err := v.readBlockTrips()
+	if err != nil || len(blocks) == 0 {
+		return err
+	}
+
+	services, err := v.readServices()
+	if err != nil {
+		return err
+	}
+	// Invalid timezones have already been reported
+	loc := time.UTC
+	err = sqlitex.Exec(v.db, "SELECT agency_timezone FROM agency ORDER BY rowid LIMIT 1", func(stmt *sqlite.Stmt) error {
+		if name := stmt.GetText("agency_timezone"); isTimezone(name) {
+			loc, _ = time.LoadLocation(name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	expander := &blockDates{services: services, loc: loc, dates: make(map[string][]int64), dayStarts: make(map[int64]int64)}
+
+	stations := make(map[string]string)
+	err = sqlitex.Exec(v.db, "SELECT stop_id, parent_station FROM stops WHERE parent_station IS NOT NULL",
+		func(stmt *sqlite.Stmt) error {
+			stations[stmt.GetText("stop_id")] = stmt.GetText("parent_station")
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+	place := func(stop string) string {
+		if station, ok := stations[stop]; ok {
+			return station
+		}
+		return stop
+	}
+
+	var ids []string
+	for id := range blocks {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	// Trips can swap order around a daylight saving change, so pairs are keyed by rowid rather than by which is later
+	type pair struct{ first, second int64 }
+	reported := make(map[pair]bool)
+	for _, id := range ids {
+		// Dates are only expanded for one block at a time
+		trips := expander.expand(blocks[id])
+		if len(trips) < 2 {
+			continue
+		}
+		slices.SortStableFunc(trips, func(a, b datedBlockTrip) int {
+			return cmp.Or(cmp.Compare(a.start, b.start), cmp.Compare(a.end, b.end))
+		})
+
+		// previous is the trip that ends last of those before
+		previous := trips[0]
+		for _, trip := range trips[1:] {
+			p := pair{min(previous.rowid, trip.rowid), max(previous.rowid, trip.rowid)}
+			if previous.rowid != trip.rowid && !reported[p] {
+				var message string
+				code := CodeOverlappingBlockTrips
+				if trip.start < previous.end {
+					message = fmt.Sprintf("trip %s in trips.txt runs %s-%s on %d, overlapping trip %s of block %s (%s-%s on %d)",
+						trip.tripID, formatBlockTime(trip, trip.start), formatBlockTime(trip, trip.end), trip.date,
+						previous.tripID, id, formatBlockTime(previous, previous.start),
+						formatBlockTime(previous, previous.end), previous.date)
+				} else if trip.start-previous.end < minLayoverSeconds && place(trip.firstStop) != place(previous.lastStop) {
+					code = CodeBlockWithoutLayover
+					message = fmt.Sprintf("trip %s in trips.txt starts from %s at %s on %d, as trip %s of block %s ends at %s, leaving no time to get there",
+						trip.tripID, trip.firstStop, formatBlockTime(trip, trip.start), trip.date,
+						previous.tripID, id, previous.lastStop)
+				}
+				if message != "" {
+					reported[p] = true
+					row, err := v.readRow("trips", trip.rowid)
+					if err != nil {
+						return err
+					}
+					v.reject(ValidationIssue{
+						Code:    code,
+						Message: message,
+						Table:   "trips",
+						Column:  "block_id",
+						RowID:   trip.rowid,
+						Value:   id,
+						Row:     row,
+					})
+				}
+			}
+			if trip.end > previous.end {
+				previous = trip
+			}
+		}
+	}
+	return nil
+}
+
+// formatBlockTime formats a time of trip relative to its service date, as GTFS times are
+func formatBlockTime(trip datedBlockTrip, t int64) string {
+	return formatTime(t - trip.dayStart)
+}
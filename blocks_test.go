@@ -0,0 +1,115 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"slices"
+	"testing"
+)
+
+func TestValidateBlocks(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"trips.txt": "route_id,service_id,trip_id,block_id\n" +
+			"AB,FULLW,OVERNIGHT,N\n" +
+			"AB,FULLW,EARLY,N\n" +
+			"AB,FULLW,L1,L\n" +
+			"AB,FULLW,L2,L\n" +
+			"AB,FULLW,L3,L\n" +
+			"AB,FULLW,W1,W\n" +
+			"AB,WE,W2,W\n",
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+			// Overlaps EARLY the next day
+			"OVERNIGHT,23:30:00,23:30:00,STAGECOACH,1\n" +
+			"OVERNIGHT,24:30:00,24:30:00,BEATTY_AIRPORT,2\n" +
+			"EARLY,00:15:00,00:15:00,BEATTY_AIRPORT,1\n" +
+			"EARLY,00:45:00,00:45:00,STAGECOACH,2\n" +
+			"L1,10:00:00,10:00:00,BULLFROG,1\n" +
+			"L1,10:30:00,10:30:00,FUR_CREEK_RES,2\n" +
+			"L2,10:30:00,10:30:00,STAGECOACH,1\n" +
+			"L2,11:00:00,11:00:00,BEATTY_AIRPORT,2\n" +
+			// Starts where L2 ends
+			"L3,11:00:00,11:00:00,BEATTY_AIRPORT,1\n" +
+			"L3,11:30:00,11:30:00,BULLFROG,2\n" +
+			// W2 only overlaps W1 at weekends
+			"W1,08:00:00,08:00:00,BEATTY_AIRPORT,1\n" +
+			"W1,08:15:00,08:15:00,BULLFROG,2\n" +
+			"W2,08:10:00,08:10:00,BULLFROG,1\n" +
+			"W2,09:00:00,09:00:00,AMV,2\n",
+	})
+
+	blockIssues := func(issues []ValidationIssue) []string {
+		var out []string
+		for _, issue := range issues {
+			if slices.Contains([]string{CodeOverlappingBlockTrips, CodeBlockWithoutLayover}, issue.Code) {
+				out = append(out, issue.Message)
+			}
+		}
+		return out
+	}
+
+	issues, err := Validate(input, nil)
+	require.ErrorIs(t, err, ErrInvalidInput)
+	assert.Equal(t, []string{
+		"trip L2 in trips.txt starts from STAGECOACH at 10:30:00 on 20070101, as trip L1 of block L ends at FUR_CREEK_RES, leaving no time to get there",
+		"trip EARLY in trips.txt runs 00:15:00-00:45:00 on 20070102, overlapping trip OVERNIGHT of block N (23:30:00-24:30:00 on 20070101)",
+		"trip W2 in trips.txt runs 08:10:00-09:00:00 on 20070106, overlapping trip W1 of block W (08:00:00-08:15:00 on 20070106)",
+	}, blockIssues(issues))
+
+	t.Run("fix", func(t *testing.T) {
+		// Overlapping trips are taken out of their block by default rather than deleted
+		outDir := testTempdir(t)
+		issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ForceValid: true})
+		require.NoError(t, err)
+		for _, issue := range issues {
+			if issue.Code == CodeOverlappingBlockTrips {
+				assert.Equal(t, RepairNullify, issue.Repair)
+				assert.Equal(t, issue.Row["trip_id"], map[int64]string{2: "EARLY", 7: "W2"}[issue.RowID])
+			}
+		}
+
+		conn, err := sqlite.OpenConn(outDir+"/imported.db", sqlite.SQLITE_OPEN_READONLY)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		var unblocked []string
+		err = sqlitex.Exec(conn, "SELECT trip_id FROM trips WHERE block_id IS NULL ORDER BY rowid", func(stmt *sqlite.Stmt) error {
+			unblocked = append(unblocked, stmt.GetText("trip_id"))
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"EARLY", "W2"}, unblocked)
+	})
+}
+
+func TestValidateBlocksDaylightSaving(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"calendar_dates.txt": "service_id,date,exception_type\n" +
+			"FULLW,20070604,2\n" +
+			"SAT,20070310,1\n" +
+			"SUN,20070311,1\n",
+		"trips.txt": "route_id,service_id,trip_id,block_id\n" +
+			"AB,SAT,OVERNIGHT,N\n" +
+			"AB,SUN,EARLY,N\n",
+		// Clocks in Los Angeles go forward on 20070311, so its times are measured from 23:00 the day before and EARLY
+		// leaves at midnight, before OVERNIGHT arrives
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+			"OVERNIGHT,23:00:00,23:00:00,STAGECOACH,1\n" +
+			"OVERNIGHT,24:50:00,24:50:00,BEATTY_AIRPORT,2\n" +
+			"EARLY,01:00:00,01:00:00,BEATTY_AIRPORT,1\n" +
+			"EARLY,01:30:00,01:30:00,STAGECOACH,2\n",
+	})
+
+	issues, err := Validate(input, nil)
+	require.ErrorIs(t, err, ErrInvalidInput)
+	var overlaps []string
+	for _, issue := range issues {
+		if issue.Code == CodeOverlappingBlockTrips {
+			overlaps = append(overlaps, issue.Message)
+		}
+	}
+	assert.Equal(t, []string{
+		"trip EARLY in trips.txt runs 01:00:00-01:30:00 on 20070311, overlapping trip OVERNIGHT of block N (23:00:00-24:50:00 on 20070310)",
+	}, overlaps)
+}
@@ -0,0 +1,338 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Dates are stored as YYYYMMDD integers. Values that aren't valid dates are skipped, as they have already been
+// reported as invalid.
+
+const dateLayout = "20060102"
+
+func dateValue(t time.Time) int64 {
+	return int64(t.Year()*10000 + int(t.Month())*100 + t.Day())
+}
+
+func parseDateValue(value int64) (time.Time, bool) {
+	t, err := time.Parse(dateLayout, fmt.Sprintf("%08d", value))
+	return t, err == nil
+}
+
+var weekdayColumns = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// sqlWeekday is an SQL expression for the day of the week of a date column, from 0 for Sunday to 6 for Saturday
+func sqlWeekday(column string) string {
+	return fmt.Sprintf("CAST(strftime('%%w', printf('%%04d-%%02d-%%02d', %s / 10000, %s / 100 %% 100, %s %% 100)) AS INTEGER)",
+		column, column, column)
+}
+
+// validateDateRange checks the end of a range of dates in table isn't before its start
+func (v *validator) validateDateRange(table, startColumn, endColumn string) error {
+	query := fmt.Sprintf(
+		"SELECT rowid, * FROM %s WHERE typeof(%s) = 'integer' AND typeof(%s) = 'integer' AND %s < %s",
+		table, startColumn, endColumn, endColumn, startColumn)
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		row := rowValues(table, stmt)
+		v.reject(ValidationIssue{
+			Code: CodeInvalidDateRange,
+			Message: fmt.Sprintf("%s %s in %s.txt is before %s %s",
+				endColumn, row[endColumn], table, startColumn, row[startColumn]),
+			Table:  table,
+			Column: endColumn,
+			RowID:  stmt.GetInt64("rowid"),
+			Value:  row[endColumn],
+			Row:    row,
+		})
+		return nil
+	})
+}
+
+// validateServiceDays checks every service in calendar.txt runs on at least one day of the week or has dates added
+// in calendar_dates.txt
+func (v *validator) validateServiceDays() error {
+	query := `
+SELECT rowid, * FROM calendar
+WHERE monday = 0 AND tuesday = 0 AND wednesday = 0 AND thursday = 0 AND friday = 0 AND saturday = 0 AND sunday = 0
+	AND service_id NOT IN (SELECT service_id FROM calendar_dates WHERE exception_type = 1 AND service_id IS NOT NULL)`
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		row := rowValues("calendar", stmt)
+		v.reject(ValidationIssue{
+			Code:    CodeServiceWithoutDays,
+			Message: fmt.Sprintf("%s in calendar.txt doesn't run on any day of the week and has no added dates", row["service_id"]),
+			Table:   "calendar",
+			Column:  "service_id",
+			RowID:   stmt.GetInt64("rowid"),
+			Value:   row["service_id"],
+			Row:     row,
+		})
+		return nil
+	})
+}
+
+// validateRedundantExceptions checks dates in calendar_dates.txt change whether the service runs, as opposed to adding
+// a date calendar.txt already has it running or removing one it doesn't. Dates listed more than once are left to be
+// reported as duplicate primary keys.
+func (v *validator) validateRedundantExceptions() error {
+	runs := "CASE " + sqlWeekday("calendar_dates.date")
+	for i, column := range weekdayColumns {
+		runs += fmt.Sprintf(" WHEN %d THEN calendar.%s", i, column)
+	}
+	runs += " END"
+	query := fmt.Sprintf(`
+SELECT * FROM (
+	SELECT calendar_dates.rowid AS rowid, calendar_dates.*,
+		CASE WHEN calendar.service_id IS NULL OR calendar_dates.date NOT BETWEEN calendar.start_date AND calendar.end_date
+			THEN 0 ELSE %s END AS __gtfs2sqlite_runs
+	FROM calendar_dates LEFT JOIN calendar ON calendar.service_id = calendar_dates.service_id
+	WHERE typeof(calendar_dates.date) = 'integer' AND NOT EXISTS (
+		SELECT 1 FROM calendar_dates AS other
+		WHERE other.service_id = calendar_dates.service_id AND other.date = calendar_dates.date
+			AND other.rowid != calendar_dates.rowid
+	)
+) WHERE (exception_type = 1 AND __gtfs2sqlite_runs = 1) OR (exception_type = 2 AND __gtfs2sqlite_runs = 0)`, runs)
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		row := rowValues("calendar_dates", stmt)
+		var message string
+		if row["exception_type"] == "1" {
+			message = fmt.Sprintf("%s in calendar_dates.txt adds %s, which calendar.txt already has it running on",
+				row["service_id"], row["date"])
+		} else {
+			message = fmt.Sprintf("%s in calendar_dates.txt removes %s, which calendar.txt doesn't have it running on",
+				row["service_id"], row["date"])
+		}
+		v.reject(ValidationIssue{
+			Code:    CodeRedundantException,
+			Message: message,
+			Table:   "calendar_dates",
+			Column:  "date",
+			RowID:   stmt.GetInt64("rowid"),
+			Value:   row["date"],
+			Row:     row,
+		})
+		return nil
+	})
+}
+
+// service is when a service_id runs according to calendar.txt and calendar_dates.txt
+type service struct {
+	days       [7]bool // from Sunday
+	start, end int64   // zero if the service isn't in calendar.txt
+	added      []int64
+	removed    map[int64]bool
+}
+
+// active is whether the service runs on any date. Services with invalid dates are assumed to be active.
+func (s *service) active() bool {
+	for _, date := range s.added {
+		if !s.removed[date] {
+			return true
+		}
+	}
+	if s.days == [7]bool{} {
+		return false
+	}
+	start, ok := parseDateValue(s.start)
+	if !ok {
+		return true
+	}
+	end, ok := parseDateValue(s.end)
+	if !ok {
+		return true
+	}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if s.days[day.Weekday()] && !s.removed[dateValue(day)] {
+			return true
+		}
+	}
+	return false
+}
+
+// dates returns the dates the service runs on in order. ok is false if they aren't known because of invalid dates.
+func (s *service) dates() (dates []int64, ok bool) {
+	set := make(map[int64]bool)
+	for _, date := range s.added {
+		if _, ok := parseDateValue(date); !ok {
+			return nil, false
+		}
+		if !s.removed[date] {
+			set[date] = true
+		}
+	}
+	if s.days != [7]bool{} {
+		start, ok := parseDateValue(s.start)
+		if !ok {
+			return nil, false
+		}
+		end, ok := parseDateValue(s.end)
+		if !ok {
+			return nil, false
+		}
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			if s.days[day.Weekday()] && !s.removed[dateValue(day)] {
+				set[dateValue(day)] = true
+			}
+		}
+	}
+
+	for date := range set {
+		dates = append(dates, date)
+	}
+	slices.Sort(dates)
+	return dates, true
+}
+
+func (v *validator) readServices() (map[string]*service, error) {
+	services := make(map[string]*service)
+	get := func(id string) *service {
+		if services[id] == nil {
+			services[id] = &service{removed: make(map[int64]bool)}
+		}
+		return services[id]
+	}
+
+	err := sqlitex.Exec(v.db, "SELECT * FROM calendar WHERE typeof(start_date) = 'integer' AND typeof(end_date) = 'integer'",
+		func(stmt *sqlite.Stmt) error {
+			s := get(stmt.GetText("service_id"))
+			for i, column := range weekdayColumns {
+				s.days[i] = stmt.GetInt64(column) == 1
+			}
+			s.start = stmt.GetInt64("start_date")
+			s.end = stmt.GetInt64("end_date")
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	err = sqlitex.Exec(v.db, "SELECT * FROM calendar_dates WHERE typeof(date) = 'integer'", func(stmt *sqlite.Stmt) error {
+		s := get(stmt.GetText("service_id"))
+		date := stmt.GetInt64("date")
+		switch stmt.GetInt64("exception_type") {
+		case 1:
+			s.added = append(s.added, date)
+		case 2:
+			s.removed[date] = true
+		}
+		return nil
+	})
+	return services, err
+}
+
+// validateTripServices checks every trip's service runs on at least one date. Unknown service_ids are reported as
+// invalid foreign IDs.
+func (v *validator) validateTripServices() error {
+	services, err := v.readServices()
+	if err != nil {
+		return err
+	}
+	inactive := make(map[string]bool)
+	for id, s := range services {
+		if !s.active() {
+			inactive[id] = true
+		}
+	}
+	if len(inactive) == 0 {
+		return nil
+	}
+
+	return sqlitex.Exec(v.db, "SELECT rowid, * FROM trips", func(stmt *sqlite.Stmt) error {
+		if !inactive[stmt.GetText("service_id")] {
+			return nil
+		}
+		row := rowValues("trips", stmt)
+		v.reject(ValidationIssue{
+			Code:    CodeNeverActiveService,
+			Message: fmt.Sprintf("%s in trips.txt is a service_id that never runs", row["service_id"]),
+			Table:   "trips",
+			Column:  "service_id",
+			RowID:   stmt.GetInt64("rowid"),
+			Value:   row["service_id"],
+			Row:     row,
+		})
+		return nil
+	})
+}
+
+// feedDate is the first or last date the feed is in effect
+type feedDate struct {
+	value       int64 // zero if unknown
+	description string
+	issue       ValidationIssue // where the date is from
+}
+
+// validateFeedDates checks the feed is in effect on the reference date, if there is one
+func (v *validator) validateFeedDates() error {
+	if v.opts.referenceDate.IsZero() {
+		return nil
+	}
+	referenceValue := dateValue(v.opts.referenceDate)
+
+	start, end, err := v.readFeedDates()
+	if err != nil {
+		return err
+	}
+	if end.value != 0 && end.value < referenceValue {
+		issue := end.issue
+		issue.Code = CodeFeedExpired
+		issue.Message = fmt.Sprintf("%s is before %d, so the feed has expired", end.description, referenceValue)
+		v.report(issue)
+	}
+	if start.value != 0 && start.value > referenceValue {
+		issue := start.issue
+		issue.Code = CodeFeedNotYetActive
+		issue.Message = fmt.Sprintf("%s is after %d, so the feed isn't in effect yet", start.description, referenceValue)
+		v.report(issue)
+	}
+	return nil
+}
+
+// readFeedDates reads the dates the feed is in effect from feed_info.txt, or if they aren't given there the first
+// and last dates of any service
+func (v *validator) readFeedDates() (start, end feedDate, err error) {
+	err = sqlitex.Exec(v.db, `
+SELECT min(date) AS start, max(date) AS end FROM (
+	SELECT start_date AS date FROM calendar WHERE typeof(start_date) = 'integer'
+	UNION ALL SELECT end_date FROM calendar WHERE typeof(end_date) = 'integer'
+	UNION ALL SELECT date FROM calendar_dates WHERE exception_type = 1 AND typeof(date) = 'integer'
+)`, func(stmt *sqlite.Stmt) error {
+		start.value = stmt.GetInt64("start")
+		start.description = fmt.Sprintf("The first service date %d", start.value)
+		start.issue = ValidationIssue{Table: "calendar"}
+		end.value = stmt.GetInt64("end")
+		end.description = fmt.Sprintf("The last service date %d", end.value)
+		end.issue = ValidationIssue{Table: "calendar"}
+		return nil
+	})
+	if err != nil {
+		return start, end, err
+	}
+
+	err = sqlitex.Exec(v.db, "SELECT rowid, * FROM feed_info LIMIT 1", func(stmt *sqlite.Stmt) error {
+		row := rowValues("feed_info", stmt)
+		for column, date := range map[string]*feedDate{"feed_start_date": &start, "feed_end_date": &end} {
+			if stmt.ColumnType(stmt.ColumnIndex(column)) != sqlite.SQLITE_INTEGER {
+				continue
+			}
+			*date = feedDate{
+				value:       stmt.GetInt64(column),
+				description: fmt.Sprintf("%s %s in feed_info.txt", column, row[column]),
+				issue: ValidationIssue{
+					Table:  "feed_info",
+					Column: column,
+					RowID:  stmt.GetInt64("rowid"),
+					Value:  row[column],
+					Row:    row,
+				},
+			}
+		}
+		return nil
+	})
+	return start, end, err
+}
@@ -0,0 +1,84 @@
+package gtfs2sqlite
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestValidateCalendar(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"calendar.txt": "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\n" +
+			"FULLW,1,1,1,1,1,1,1,20070101,20101231\n" +
+			// Never runs, as the only Saturday is removed
+			"WE,0,0,0,0,0,1,0,20070601,20070604\n" +
+			"BACKWARDS,1,1,1,1,1,0,0,20101231,20070101\n" +
+			"NO_DAYS,0,0,0,0,0,0,0,20070101,20101231\n",
+		"calendar_dates.txt": "service_id,date,exception_type\n" +
+			"WE,20070602,2\n" +
+			// Redundant
+			"FULLW,20070605,1\n" +
+			"WE,20070604,2\n" +
+			// A missing service_id doesn't hide services without days
+			",20070605,1\n",
+	})
+
+	issues, err := Validate(input, &ValidateOpts{ReferenceDate: sampleDate})
+	require.ErrorIs(t, err, ErrInvalidInput)
+
+	got := make(map[string][]string)
+	for _, issue := range issues {
+		got[issue.Code] = append(got[issue.Code], issue.Message)
+	}
+	assert.Equal(t, []string{"end_date 20070101 in calendar.txt is before start_date 20101231"}, got[CodeInvalidDateRange])
+	assert.Equal(t, []string{"NO_DAYS in calendar.txt doesn't run on any day of the week and has no added dates"}, got[CodeServiceWithoutDays])
+	assert.ElementsMatch(t, []string{
+		"FULLW in calendar_dates.txt adds 20070605, which calendar.txt already has it running on",
+		"WE in calendar_dates.txt removes 20070604, which calendar.txt doesn't have it running on",
+	}, got[CodeRedundantException])
+	assert.Len(t, got[CodeNeverActiveService], 4)
+	assert.Contains(t, got[CodeNeverActiveService], "WE in trips.txt is a service_id that never runs")
+}
+
+func TestValidateFeedDates(t *testing.T) {
+	t.Run("calendar", func(t *testing.T) {
+		issues, err := Validate("./sample_data/sample-feed.zip", &ValidateOpts{ReferenceDate: time.Date(2011, 1, 1, 0, 0, 0, 0, time.UTC)})
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		assert.Equal(t, CodeFeedExpired, issues[0].Code)
+		assert.Equal(t, SeverityWarning, issues[0].Severity)
+		assert.Equal(t, "The last service date 20101231 is before 20110101, so the feed has expired", issues[0].Message)
+
+		issues, err = Validate("./sample_data/sample-feed.zip", &ValidateOpts{ReferenceDate: time.Date(2006, 1, 1, 0, 0, 0, 0, time.UTC)})
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		assert.Equal(t, "The first service date 20070101 is after 20060101, so the feed isn't in effect yet", issues[0].Message)
+	})
+	t.Run("feed_info", func(t *testing.T) {
+		input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+			"feed_info.txt": "feed_publisher_name,feed_publisher_url,feed_lang,feed_start_date,feed_end_date\n" +
+				"Demo,https://example.com,en,20070101,20071231\n",
+		})
+		issues, err := Validate(input, &ValidateOpts{ReferenceDate: sampleDate})
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		assert.Equal(t, CodeFeedExpired, issues[0].Code)
+		assert.Equal(t, "feed_info", issues[0].Table)
+		assert.Equal(t, "feed_end_date", issues[0].Column)
+		assert.Equal(t, "feed_end_date 20071231 in feed_info.txt is before 20080101, so the feed has expired", issues[0].Message)
+	})
+	t.Run("import", func(t *testing.T) {
+		// Without a reference date importing doesn't depend on today's date
+		outDir := testTempdir(t)
+		issues, err := Import("./sample_data/sample-feed.zip", outDir+"/feed.db", nil)
+		require.NoError(t, err)
+		assert.Empty(t, issues)
+
+		// Validate checks against today
+		issues, err = Validate("./sample_data/sample-feed.zip", nil)
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		assert.Equal(t, CodeFeedExpired, issues[0].Code)
+	})
+}
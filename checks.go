@@ -0,0 +1,41 @@
+package gtfs2sqlite
+
+// tableCheck is a check over the rows of Table that can't be expressed in the schema
+type tableCheck struct {
+	Table string
+	// Reads lists the other tables the check queries, so it is run again when they change
+	Reads []string
+	Check func(v *validator) error
+}
+
+var tableChecks = []tableCheck{
+	{Table: "stop_times", Check: (*validator).validateArrivalBeforeDeparture},
+	{Table: "stop_times", Check: (*validator).validateStopTimesIncrease},
+	{Table: "stop_times", Check: (*validator).validateShapeDistanceIncreases},
+	{Table: "stop_times", Check: (*validator).validateRepeatedStops},
+	{Table: "stop_times", Reads: []string{"stops", "trips", "routes"}, Check: (*validator).validateTravelSpeed},
+	{Table: "trips", Reads: []string{"stop_times"}, Check: (*validator).validateTripStopTimeCount},
+
+	{Table: "calendar", Check: func(v *validator) error { return v.validateDateRange("calendar", "start_date", "end_date") }},
+	{Table: "feed_info", Check: func(v *validator) error { return v.validateDateRange("feed_info", "feed_start_date", "feed_end_date") }},
+	{Table: "calendar", Reads: []string{"calendar_dates"}, Check: (*validator).validateServiceDays},
+	{Table: "calendar_dates", Reads: []string{"calendar"}, Check: (*validator).validateRedundantExceptions},
+	{Table: "trips", Reads: []string{"calendar", "calendar_dates"}, Check: (*validator).validateTripServices},
+	// Checked with calendar as feed_info.txt is optional
+	{Table: "calendar", Reads: []string{"calendar_dates", "feed_info"}, Check: (*validator).validateFeedDates},
+
+	{Table: "translations", Check: (*validator).validateTranslationFields},
+	{Table: "translations", Reads: translatableTables, Check: (*validator).validateTranslationRecords},
+	{Table: "translations", Reads: translatableTables, Check: (*validator).validateTranslationValues},
+
+	{Table: "stops", Check: (*validator).validateParentStations},
+	{Table: "pathways", Reads: []string{"stops"}, Check: (*validator).validatePathways},
+	{Table: "pathways", Check: (*validator).validatePathwayDirections},
+
+	{Table: "agency", Check: (*validator).validateAgencyTimezones},
+	{Table: "stops", Reads: []string{"agency"}, Check: (*validator).validateChildStopTimezones},
+	{Table: "stops", Reads: []string{"agency"}, Check: (*validator).validateStopTimezoneLocations},
+
+	{Table: "trips", Reads: []string{"stop_times", "calendar", "calendar_dates", "frequencies", "stops"},
+		Check: (*validator).validateBlocks},
+}
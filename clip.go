@@ -46,6 +46,16 @@ func Clip(inputPath string, outputPath string, clipFeature string) error {
 	}
 	slog.Info("Copied input db")
 
+	legacy, err := legacyTables(db)
+	if err != nil {
+		return err
+	}
+	if len(legacy) > 0 {
+		if err := migrateLegacyTables(db, legacy); err != nil {
+			return err
+		}
+	}
+
 	if err := sqlitex.ExecTransient(db, "CREATE TABLE __gtfs2sqlite_stops_inside (stop_id TEXT)", sqlitexNoop); err != nil {
 		return err
 	}
@@ -83,19 +93,19 @@ DELETE FROM trips
 
 DELETE FROM stop_times WHERE trip_id NOT IN (SELECT DISTINCT trip_id FROM trips);
 
-DELETE FROM stops WHERE stop_id NOT IN
-	(SELECT DISTINCT stop_id FROM stop_times
-	 UNION SELECT DISTINCT parent_station FROM stops WHERE parent_station IS NOT NULL);
+DELETE FROM stops WHERE ` + unusedCondition("stops") + `;
+DELETE FROM levels WHERE ` + unusedCondition("levels") + `;
 
 DELETE FROM stop_areas WHERE stop_id NOT IN (SELECT DISTINCT stop_id FROM stops);
 DELETE FROM areas WHERE area_id NOT IN (SELECT DISTINCT area_id FROM stop_areas);
 
-DELETE FROM routes WHERE route_id NOT IN (SELECT DISTINCT route_id FROM trips);
+DELETE FROM routes WHERE ` + unusedCondition("routes") + `;
+DELETE FROM shapes WHERE ` + unusedCondition("shapes") + `;
 
-DELETE FROM agency WHERE agency_id NOT IN (SELECT DISTINCT agency_id FROM routes);
+DELETE FROM agency WHERE ` + unusedCondition("agency") + `;
 
-DELETE FROM calendar WHERE service_id NOT IN (SELECT DISTINCT service_id FROM trips);
-DELETE FROM calendar_dates WHERE service_id NOT IN (SELECT DISTINCT service_id FROM trips);
+DELETE FROM calendar WHERE ` + unusedCondition("calendar") + `;
+DELETE FROM calendar_dates WHERE ` + unusedCondition("calendar_dates") + `;
 
 DELETE FROM transfers WHERE
   (from_stop_id IS NOT NULL AND from_stop_id NOT IN (SELECT DISTINCT stop_id FROM stops)) OR
@@ -120,7 +130,7 @@ DELETE FROM attributions WHERE
 
 DELETE FROM fare_rules WHERE route_id IS NOT NULL AND route_id NOT IN (SELECT DISTINCT route_id FROM routes);
 DELETE FROM fare_attributes WHERE
-  (fare_id NOT IN (SELECT DISTINCT fare_id FROM fare_rules)) OR
+  (` + unusedCondition("fare_attributes") + `) OR
   (agency_id IS NOT NULL AND agency_id NOT IN (SELECT agency_id FROM agency));
 
 DELETE FROM fare_leg_rules WHERE
@@ -0,0 +1,62 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestClipUnused(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station,level_id\n" +
+			"FUR_CREEK_RES,Furnace Creek Resort (Demo),36.425288,-117.133162,,,L2\n" +
+			"BEATTY_AIRPORT,Nye County Airport (Demo),36.868446,-116.784582,,,\n" +
+			"BULLFROG,Bullfrog (Demo),36.88108,-116.81797,,,\n" +
+			"STAGECOACH_STATION,Stagecoach Hotel & Casino Station (Demo),36.915682,-116.751677,1,,\n" +
+			"STAGECOACH,Stagecoach Hotel & Casino (Demo),36.915682,-116.751677,,STAGECOACH_STATION,\n" +
+			"STAGECOACH_ENTRANCE,Stagecoach Hotel & Casino Entrance (Demo),36.915682,-116.751677,2,STAGECOACH_STATION,L1\n" +
+			"NADAV,North Ave / D Ave N (Demo),36.914893,-116.76821,,,\n" +
+			"NANAA,North Ave / N A Ave (Demo),36.914944,-116.761472,,,\n" +
+			"DADAN,Doing Ave / D Ave N (Demo),36.909489,-116.768242,,,\n" +
+			"EMSI,E Main St / S Irving St (Demo),36.905697,-116.76218,,,\n" +
+			"AMV,Amargosa Valley (Demo),36.641496,-116.40094,,,\n",
+		"levels.txt": "level_id,level_index\n" +
+			"L1,0\n" +
+			"L2,0\n",
+	})
+	outDir := testTempdir(t)
+	_, err := Import(input, outDir+"/imported.db", &ImportOpts{ReferenceDate: sampleDate})
+	require.NoError(t, err)
+
+	feature, err := os.ReadFile("./sample_data/ne_beatty.json")
+	require.NoError(t, err)
+	require.NoError(t, Clip(outDir+"/imported.db", outDir+"/clipped.db", string(feature)))
+
+	conn, err := sqlite.OpenConn(outDir+"/clipped.db", sqlite.SQLITE_OPEN_READONLY)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	ids := func(query string) []string {
+		var out []string
+		err := sqlitex.Exec(conn, query, func(stmt *sqlite.Stmt) error {
+			out = append(out, stmt.ColumnText(0))
+			return nil
+		})
+		require.NoError(t, err)
+		return out
+	}
+
+	// Clip keeps what validation doesn't warn about, so the entrance of a station still served is kept along with its
+	// level
+	assert.Subset(t, ids("SELECT stop_id FROM stops"), []string{"STAGECOACH", "STAGECOACH_STATION", "STAGECOACH_ENTRANCE"})
+	assert.NotContains(t, ids("SELECT stop_id FROM stops"), "FUR_CREEK_RES")
+	assert.Equal(t, []string{"L1"}, ids("SELECT level_id FROM levels"))
+
+	issues, err := Validate(outDir+"/clipped.db", &ValidateOpts{ReferenceDate: sampleDate})
+	require.NoError(t, err)
+	for _, issue := range issues {
+		assert.NotEqual(t, CodeUnusedEntity, issue.Code, issue.Message)
+	}
+}
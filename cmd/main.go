@@ -6,7 +6,9 @@ import (
 	"github.com/spf13/pflag"
 	"os"
 	"path"
+	"slices"
 	"strings"
+	"time"
 )
 
 /* Timing notes on UK rail timetable:
@@ -19,6 +21,8 @@ func usageAndDie() {
 	fmt.Println("Example usage:\n" +
 		"    gtfs2sqlite --import <timetable.zip>\n" +
 		"    gtfs2sqlite --export <timetable.db>\n" +
+		"    gtfs2sqlite --validate <timetable.zip|timetable.db>\n" +
+		"    gtfs2sqlite --restore-removed <timetable.db>\n" +
 		"    gtfs2sqlite --clip <timetable.db> --clip-feature <feature_geojson.json>")
 	os.Exit(1)
 }
@@ -27,11 +31,20 @@ func main() {
 	importPath := pflag.StringP("import", "i", "", "Import from a GTFS file")
 	exportPath := pflag.StringP("export", "e", "", "Export to a GTFS file")
 	clipPath := pflag.StringP("clip", "c", "", "Clip a database")
-	primaryOptions := []*string{importPath, exportPath, clipPath}
+	validatePath := pflag.String("validate", "", "Check a GTFS file or database for issues without writing any output")
+	restorePath := pflag.String("restore-removed", "", "Reinsert rows deleted by --force-valid into a database")
+	primaryOptions := []*string{importPath, exportPath, clipPath, validatePath, restorePath}
 
 	output := pflag.StringP("out", "o", "", "Path to write output to")
-	forceMode := pflag.BoolP("force-valid", "f", false, "Whether to fix issues by deleting data during import")
-	ignoreInvalidMode := pflag.Bool("ignore-invalid", false, "Ignore any issues during import")
+	forceMode := pflag.BoolP("force-valid", "f", false, "Whether to fix errors during import, by deleting data or clearing invalid optional references unless --rules configures other repairs")
+	dryRun := pflag.Bool("dry-run", false, "With --force-valid, list the rows that would be repaired without writing any output")
+	coerceEnums := pflag.Bool("coerce-enums", false, "With --force-valid, replace invalid enum values with their default instead of deleting the row")
+	ignoreInvalidMode := pflag.Bool("ignore-invalid", false, "Import even if there are errors")
+	reportPath := pflag.String("report", "", "Write the issues found to a JSON file, or an HTML summary if the path ends in .html")
+	rulesPath := pflag.String("rules", "", "Configure the checks run from a JSON file, see gtfs2sqlite.Rules")
+	referenceDate := pflag.String("reference-date", "", "Check the feed is in effect on this date (YYYYMMDD), by default today with --validate")
+	skipIndexes := pflag.Bool("skip-indexes", false, "Don't create indexes during import")
+	restoreTables := pflag.StringSlice("restore-tables", nil, "If --restore-removed is specified only restore rows to these tables")
 	clipFeaturePath := pflag.String("clip-feature", "", "If --clip is specified clips to the GeoJSON feature in the file specified")
 
 	pflag.Parse()
@@ -46,14 +59,84 @@ func main() {
 		usageAndDie()
 	}
 
+	// --validate never writes output, so it can only show what --force-valid would do
+	if *validatePath != "" && *forceMode && !*dryRun {
+		fmt.Println("Error: --force-valid can only be used with --validate along with --dry-run")
+		os.Exit(1)
+	}
+
+	var rules *gtfs2sqlite.Rules
+	if *rulesPath != "" {
+		data, err := os.ReadFile(*rulesPath)
+		if err != nil {
+			panic(err)
+		}
+		rules, err = gtfs2sqlite.ParseRules(data)
+		if err != nil {
+			fmt.Printf("Error: invalid rules: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var reference time.Time
+	if *referenceDate != "" {
+		var err error
+		reference, err = time.Parse("20060102", *referenceDate)
+		if err != nil {
+			fmt.Printf("Error: invalid --reference-date: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
 	var err error
-	if *importPath != "" {
+	if *dryRun {
+		inputPath := *importPath
+		if inputPath == "" {
+			inputPath = *validatePath
+		}
+		if inputPath == "" || !*forceMode {
+			usageAndDie()
+		}
+		opts := &gtfs2sqlite.ValidateOpts{
+			Rules:              rules,
+			CoerceInvalidEnums: *coerceEnums,
+			ReferenceDate:      reference,
+		}
+		var issues []gtfs2sqlite.ValidationIssue
+		var removed []gtfs2sqlite.RemovedRow
+		issues, removed, err = gtfs2sqlite.DryRunForceValid(inputPath, opts)
+		writeReportOrDie(issues, *reportPath)
+		printDryRun(issues, removed)
+	} else if *importPath != "" {
 		outputPath := outputPathOrDefault(*importPath, *output, ".zip", ".db")
 		opts := &gtfs2sqlite.ImportOpts{
-			ForceValid:    *forceMode,
-			IgnoreInvalid: *ignoreInvalidMode,
+			ForceValid:         *forceMode,
+			CoerceInvalidEnums: *coerceEnums,
+			IgnoreInvalid:      *ignoreInvalidMode,
+			SkipIndexes:        *skipIndexes,
+			Rules:              rules,
+			ReferenceDate:      reference,
+		}
+		var issues []gtfs2sqlite.ValidationIssue
+		issues, err = gtfs2sqlite.Import(*importPath, outputPath, opts)
+		reportIssues(issues, *reportPath)
+	} else if *validatePath != "" {
+		var issues []gtfs2sqlite.ValidationIssue
+		issues, err = gtfs2sqlite.Validate(*validatePath, &gtfs2sqlite.ValidateOpts{Rules: rules, ReferenceDate: reference})
+		reportIssues(issues, *reportPath)
+	} else if *restorePath != "" {
+		var restored int
+		var skipped []gtfs2sqlite.RemovedRow
+		restored, skipped, err = gtfs2sqlite.RestoreRemoved(*restorePath, &gtfs2sqlite.RestoreOpts{Tables: *restoreTables})
+		if err == nil {
+			fmt.Printf("Restored %d row(s)\n", restored)
+			if len(skipped) > 0 {
+				fmt.Printf("Skipped %d row(s) whose primary key is now used by another row:\n", len(skipped))
+				for _, row := range skipped {
+					fmt.Printf("    %s.txt row %d: %s\n", row.Table, row.RowID, row)
+				}
+			}
 		}
-		_, err = gtfs2sqlite.Import(*importPath, outputPath, opts)
 	} else if *exportPath != "" {
 		outputPath := outputPathOrDefault(*exportPath, *output, ".db", ".zip")
 		opts := &gtfs2sqlite.ExportOpts{}
@@ -83,6 +166,115 @@ func main() {
 	}
 }
 
+func reportIssues(issues []gtfs2sqlite.ValidationIssue, reportPath string) {
+	printIssueSummary(issues)
+	writeReportOrDie(issues, reportPath)
+}
+
+func writeReportOrDie(issues []gtfs2sqlite.ValidationIssue, reportPath string) {
+	if reportPath != "" {
+		if err := writeReport(reportPath, issues); err != nil {
+			fmt.Printf("Error writing report: %s\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func printIssueSummary(issues []gtfs2sqlite.ValidationIssue) {
+	if len(issues) == 0 {
+		return
+	}
+
+	counts := make(map[string]int)
+	var keys []string
+	repaired := 0
+	for _, issue := range issues {
+		key := fmt.Sprintf("%s %s", issue.Severity, issue.Code)
+		if counts[key] == 0 {
+			keys = append(keys, key)
+		}
+		counts[key]++
+		if issue.Repair != "" {
+			repaired++
+		}
+	}
+	slices.Sort(keys)
+
+	fmt.Printf("Found %d issue(s):\n", len(issues))
+	for _, key := range keys {
+		fmt.Printf("    %6d %s\n", counts[key], key)
+	}
+	if repaired > 0 {
+		fmt.Printf("Repaired %d of them\n", repaired)
+	}
+}
+
+// printDryRun summarizes the values --force-valid would repair and lists every row it would delete
+func printDryRun(issues []gtfs2sqlite.ValidationIssue, removed []gtfs2sqlite.RemovedRow) {
+	counts := make(map[string]int)
+	var keys []string
+	count := func(key string) {
+		if counts[key] == 0 {
+			keys = append(keys, key)
+		}
+		counts[key]++
+	}
+
+	var repaired []gtfs2sqlite.ValidationIssue
+	for _, issue := range issues {
+		if issue.Repair == "" || issue.Deleted {
+			continue
+		}
+		count(fmt.Sprintf("%s in %s.txt", issue.Repair, issue.Table))
+		repaired = append(repaired, issue)
+	}
+	for _, row := range removed {
+		count(fmt.Sprintf("delete in %s.txt", row.Table))
+	}
+	slices.Sort(keys)
+
+	if len(repaired) == 0 && len(removed) == 0 {
+		fmt.Println("--force-valid wouldn't change anything")
+		return
+	}
+
+	fmt.Printf("Found %d issue(s), --force-valid would repair %d value(s) and delete %d row(s):\n",
+		len(issues), len(repaired), len(removed))
+	for _, key := range keys {
+		fmt.Printf("    %6d %s\n", counts[key], key)
+	}
+	if len(repaired) > 0 {
+		fmt.Println("Repairs:")
+		for _, issue := range repaired {
+			fmt.Printf("    (pass %d, %s) %s\n", issue.Pass, issue.Repair, issue)
+		}
+	}
+	if len(removed) > 0 {
+		fmt.Println("Deleted rows:")
+		for _, row := range removed {
+			fmt.Printf("    (pass %d) %s.txt row %d: %s\n", row.Pass, row.Table, row.RowID, row)
+		}
+	}
+}
+
+func writeReport(reportPath string, issues []gtfs2sqlite.ValidationIssue) error {
+	f, err := os.Create(reportPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	if strings.HasSuffix(reportPath, ".html") {
+		err = gtfs2sqlite.WriteHTMLReport(f, issues)
+	} else {
+		err = gtfs2sqlite.WriteJSONReport(f, issues)
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
 func outputPathOrDefault(inputPath string, outputPath string, suffixToTrim string, newSuffix string) string {
 	if outputPath != "" {
 		return outputPath
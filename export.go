@@ -71,6 +71,7 @@ func Export(inputPath string, outputPath string, opts *ExportOpts) error {
 		}
 	}
 
+	hasOriginalText := slices.Contains(tables, "__gtfs2sqlite_original_text")
 	for _, table := range tables {
 		if strings.HasPrefix(table, "__gtfs2sqlite") {
 			continue
@@ -88,7 +89,7 @@ func Export(inputPath string, outputPath string, opts *ExportOpts) error {
 			continue
 		}
 
-		if err := exportTableIn(db, outputZip, table); err != nil {
+		if err := exportTableIn(db, outputZip, table, hasOriginalText); err != nil {
 			return err
 		}
 	}
@@ -110,7 +111,7 @@ func Export(inputPath string, outputPath string, opts *ExportOpts) error {
 	return nil
 }
 
-func exportTableIn(db *sqlite.Conn, outputZip *zip.Writer, table string) error {
+func exportTableIn(db *sqlite.Conn, outputZip *zip.Writer, table string, hasOriginalText bool) error {
 	outputName := table + ".txt"
 	outputF, err := outputZip.Create(outputName)
 	if err != nil {
@@ -136,20 +137,62 @@ func exportTableIn(db *sqlite.Conn, outputZip *zip.Writer, table string) error {
 	}
 	rowCount++
 
-	err = sqlitex.Exec(db, "SELECT * FROM "+table, func(stmt *sqlite.Stmt) error {
-		var row []string
-		for _, col := range cols {
-			row = append(row, stmt.GetText(col))
+	kinds := make([]valueKind, len(cols))
+	for i, col := range cols {
+		kinds[i] = gtfsSchema[table].Columns[col].kind()
+	}
+
+	// Each row is joined with any original text saved for it during import, so a row
+	// can span multiple results.
+	query := "SELECT rowid AS __gtfs2sqlite_rowid, *, NULL AS __gtfs2sqlite_column, NULL AS __gtfs2sqlite_value, NULL AS __gtfs2sqlite_text FROM " + table
+	if hasOriginalText {
+		query = fmt.Sprintf(`SELECT t.rowid AS __gtfs2sqlite_rowid, t.*,
+				o.columnName AS __gtfs2sqlite_column, o.value AS __gtfs2sqlite_value, o.text AS __gtfs2sqlite_text
+			FROM %s AS t
+			LEFT JOIN __gtfs2sqlite_original_text AS o ON o.tableName = '%s' AND o.rowID = t.rowid
+			ORDER BY t.rowid`, table, table)
+	}
+
+	var row []string
+	var rowid int64
+	writeRow := func() error {
+		if row == nil {
+			return nil
 		}
 		if err := outputCSV.Write(row); err != nil {
 			return err
 		}
 		rowCount++
+		row = nil
+		return nil
+	}
+	err = sqlitex.Exec(db, query, func(stmt *sqlite.Stmt) error {
+		if row == nil || stmt.GetInt64("__gtfs2sqlite_rowid") != rowid {
+			if err := writeRow(); err != nil {
+				return err
+			}
+			rowid = stmt.GetInt64("__gtfs2sqlite_rowid")
+			for i := range cols {
+				// Offset by one for __gtfs2sqlite_rowid
+				row = append(row, formatValue(kinds[i], columnValue(stmt, i+1)))
+			}
+		}
+
+		// Use the original text if the value hasn't been changed since import
+		if originalCol := stmt.GetText("__gtfs2sqlite_column"); originalCol != "" {
+			i := slices.Index(cols, originalCol)
+			if i != -1 && stmt.ColumnText(i+1) == stmt.GetText("__gtfs2sqlite_value") {
+				row[i] = stmt.GetText("__gtfs2sqlite_text")
+			}
+		}
 		return nil
 	})
 	if err != nil {
 		return err
 	}
+	if err := writeRow(); err != nil {
+		return err
+	}
 	slog.Info(fmt.Sprintf("Wrote %d rows to %s", rowCount, outputName))
 
 	outputCSV.Flush()
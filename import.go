@@ -11,18 +11,35 @@ import (
 	"log/slog"
 	"os"
 	"strings"
+	"time"
 )
 
 type ImportOpts struct {
-	ForceValid    bool
+	// ForceValid fixes errors by repairing the rows with them, which means deleting them unless Rules configures
+	// other repairs. Warnings are left as they are. Errors it won't repair, such as agencies with different
+	// timezones, still return ErrInvalidInput.
+	ForceValid bool
+	// CoerceInvalidEnums makes ForceValid replace invalid enum values with the default value for the column
+	// instead of deleting the row. Rows with invalid values in columns that have no default are still deleted.
+	// It is a shorthand for RepairDefault, and is ignored if Rules configures repairs for invalid enums.
+	CoerceInvalidEnums bool
+	// IgnoreInvalid imports feeds with errors as they are instead of returning ErrInvalidInput
 	IgnoreInvalid bool
+	// SkipIndexes skips creating indexes on primary keys and foreign IDs
+	SkipIndexes bool
+	// Rules configures the checks run on import. If nil every check is run with its default severity.
+	Rules *Rules
+	// ReferenceDate is the date the feed should be in effect on. Unlike Validate, which checks against today, Import
+	// doesn't check the feed's dates if it is zero, so that whether an import succeeds doesn't depend on when it is
+	// run. Set it to time.Now() to check the feed hasn't expired.
+	ReferenceDate time.Time
 }
 
 var importPragmas = map[string]string{
 	"synchronous": "OFF",
 }
 
-func Import(inputPath string, outputPath string, opts *ImportOpts) ([]string, error) {
+func Import(inputPath string, outputPath string, opts *ImportOpts) ([]ValidationIssue, error) {
 	if inputPath == "" {
 		panic("Missing inputPath")
 	}
@@ -57,24 +74,9 @@ func Import(inputPath string, outputPath string, opts *ImportOpts) ([]string, er
 		}
 	}()
 
-	for pragma, value := range importPragmas {
-		err = sqlitex.Exec(db, "PRAGMA "+pragma+" = "+value, sqlitexNoop)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	for table, schema := range gtfsSchema {
-		if err := createTable(db, table, schema); err != nil {
-			return nil, err
-		}
-	}
-
-	for _, filename := range inputZip.File {
-		err = importFileIn(inputZip, db, filename.Name)
-		if err != nil {
-			return nil, err
-		}
+	nonUniqueTables, err := importInto(db, inputZip, opts.SkipIndexes)
+	if err != nil {
+		return nil, err
 	}
 
 	var validationLogLevel slog.Level
@@ -85,14 +87,26 @@ func Import(inputPath string, outputPath string, opts *ImportOpts) ([]string, er
 	}
 
 	validationErrors, err := validate(db, validateOpts{
-		force:    opts.ForceValid,
-		ignore:   opts.IgnoreInvalid,
-		logLevel: validationLogLevel,
+		force:         opts.ForceValid,
+		coerceEnums:   opts.CoerceInvalidEnums,
+		ignore:        opts.IgnoreInvalid,
+		rules:         opts.Rules,
+		logLevel:      validationLogLevel,
+		referenceDate: opts.ReferenceDate,
 	})
 	if err != nil {
 		return validationErrors, err
 	}
 
+	if opts.ForceValid {
+		// Duplicates have been removed
+		for _, table := range nonUniqueTables {
+			if _, err := createPrimaryKeyIndex(db, table, gtfsSchema[table]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	err = db.Close()
 	db = nil
 	if err != nil {
@@ -103,15 +117,116 @@ func Import(inputPath string, outputPath string, opts *ImportOpts) ([]string, er
 	return validationErrors, nil
 }
 
+// importInto imports a feed into an empty database, returning the tables whose primary key index couldn't be
+// unique because of duplicates
+func importInto(db *sqlite.Conn, inputZip *zip.ReadCloser, skipIndexes bool) ([]string, error) {
+	for pragma, value := range importPragmas {
+		err := sqlitex.Exec(db, "PRAGMA "+pragma+" = "+value, sqlitexNoop)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for table, schema := range gtfsSchema {
+		if err := createTable(db, table, schema); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := sqlitex.ExecScript(db, originalTextSchema); err != nil {
+		return nil, err
+	}
+
+	for _, filename := range inputZip.File {
+		if err := importFileIn(inputZip, db, filename.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	if skipIndexes {
+		return nil, nil
+	}
+	return createIndexes(db)
+}
+
 func createTable(db *sqlite.Conn, table string, schema tableSchema) error {
 	var columnFragments []string
-	for column, _ := range schema.Columns {
-		columnFragments = append(columnFragments, column+" TEXT")
+	for column, columnSchema := range schema.Columns {
+		columnFragments = append(columnFragments, column+" "+columnSchema.kind().sqlType())
 	}
 	query := fmt.Sprintf("CREATE TABLE %s (%s)", table, strings.Join(columnFragments, ", "))
 	return sqlitex.ExecTransient(db, query, sqlitexNoop)
 }
 
+// createIndexes returns the tables whose primary key index couldn't be unique because of duplicates
+func createIndexes(db *sqlite.Conn) ([]string, error) {
+	slog.Info("Creating indexes")
+
+	var nonUniqueTables []string
+	indexed := make(map[string]bool) // table.column of the first column of each index
+	for table, schema := range gtfsSchema {
+		if len(schema.PrimaryKey) == 0 {
+			continue
+		}
+		unique, err := createPrimaryKeyIndex(db, table, schema)
+		if err != nil {
+			return nil, err
+		}
+		if !unique {
+			nonUniqueTables = append(nonUniqueTables, table)
+		}
+		indexed[table+"."+schema.PrimaryKey[0]] = true
+	}
+
+	createIndex := func(table, column string) error {
+		if indexed[table+"."+column] {
+			return nil
+		}
+		indexed[table+"."+column] = true
+		query := fmt.Sprintf("CREATE INDEX %s_%s_idx ON %s (%s)", table, column, table, column)
+		return sqlitex.ExecTransient(db, query, sqlitexNoop)
+	}
+	for table, schema := range gtfsSchema {
+		for column, columnSchema := range schema.Columns {
+			if columnSchema.ForeignID == nil {
+				continue
+			}
+			if err := createIndex(table, column); err != nil {
+				return nil, err
+			}
+
+			targets := columnSchema.ForeignID.AnyOf
+			if len(targets) == 0 {
+				targets = []foreignIDSchema{*columnSchema.ForeignID}
+			}
+			for _, target := range targets {
+				if err := createIndex(target.Table, target.Column); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return nonUniqueTables, nil
+}
+
+// createPrimaryKeyIndex (re)creates the index on the primary key, falling back to a non-unique index if the
+// table has duplicates.
+func createPrimaryKeyIndex(db *sqlite.Conn, table string, schema tableSchema) (unique bool, err error) {
+	if err := sqlitex.ExecTransient(db, fmt.Sprintf("DROP INDEX IF EXISTS %s_pkey", table), sqlitexNoop); err != nil {
+		return false, err
+	}
+
+	columns := strings.Join(schema.PrimaryKey, ", ")
+	query := fmt.Sprintf("CREATE UNIQUE INDEX %s_pkey ON %s (%s)", table, table, columns)
+	err = sqlitex.ExecTransient(db, query, sqlitexNoop)
+	if sqlite.ErrCode(err) == sqlite.SQLITE_CONSTRAINT_UNIQUE {
+		slog.Warn(fmt.Sprintf("%s.txt has duplicate primary keys, creating a non-unique index instead", table))
+		query = fmt.Sprintf("CREATE INDEX %s_pkey ON %s (%s)", table, table, columns)
+		return false, sqlitex.ExecTransient(db, query, sqlitexNoop)
+	}
+	return err == nil, err
+}
+
 func importFileIn(inputZip *zip.ReadCloser, db *sqlite.Conn, filename string) error {
 	inputF, err := inputZip.Open(filename)
 	if err != nil {
@@ -147,6 +262,16 @@ func importFileIn(inputZip *zip.ReadCloser, db *sqlite.Conn, filename string) er
 	}
 	slog.Info(fmt.Sprintf("Importing %s: %s", filename, strings.Join(header, ",")))
 
+	// Recorded so the validator can tell a missing column from one that is always empty
+	if err := sqlitex.Exec(db, "CREATE TABLE IF NOT EXISTS __gtfs2sqlite_file_columns (tableName TEXT, columnName TEXT)", sqlitexNoop); err != nil {
+		return err
+	}
+	for _, column := range header {
+		if err := sqlitex.Exec(db, "INSERT INTO __gtfs2sqlite_file_columns (tableName, columnName) VALUES (?, ?)", sqlitexNoop, table, column); err != nil {
+			return err
+		}
+	}
+
 	var unknownColumns []string
 	for _, column := range header {
 		if _, ok := gtfsSchema[table].Columns[column]; !ok {
@@ -181,6 +306,15 @@ func importFileIn(inputZip *zip.ReadCloser, db *sqlite.Conn, filename string) er
 		return err
 	}
 
+	kinds := make([]valueKind, len(header))
+	for i, column := range header {
+		kinds[i] = gtfsSchema[table].Columns[column].kind()
+	}
+
+	// Values that wouldn't be exported exactly as they were imported are saved so that
+	// the export round-trips
+	var originalTexts []originalText
+
 	// Rows
 
 	inputCSV.FieldsPerRecord = -1 // Allow variable numbers of fields
@@ -203,12 +337,33 @@ func importFileIn(inputZip *zip.ReadCloser, db *sqlite.Conn, filename string) er
 			return err
 		}
 
+		originalTexts = originalTexts[:0]
 		for i, v := range row {
 			param := i + 1
 			if v == "" {
 				insertStmt.BindNull(param)
-			} else {
+				continue
+			}
+			if i >= len(kinds) || kinds[i] == textKind {
+				insertStmt.BindText(param, v)
+				continue
+			}
+
+			value, ok := parseValue(kinds[i], v)
+			if !ok {
+				// The validator reports values that couldn't be parsed
 				insertStmt.BindText(param, v)
+				originalTexts = append(originalTexts, originalText{column: header[i], value: v, text: v})
+				continue
+			}
+			switch value := value.(type) {
+			case int64:
+				insertStmt.BindInt64(param, value)
+			case float64:
+				insertStmt.BindFloat(param, value)
+			}
+			if formatValue(kinds[i], value) != v {
+				originalTexts = append(originalTexts, originalText{column: header[i], value: value, text: v})
 			}
 		}
 
@@ -222,6 +377,15 @@ func importFileIn(inputZip *zip.ReadCloser, db *sqlite.Conn, filename string) er
 			}
 		}
 
+		if len(originalTexts) > 0 {
+			rowid := db.LastInsertRowID()
+			for _, original := range originalTexts {
+				if err := saveOriginalText(db, table, rowid, original); err != nil {
+					return err
+				}
+			}
+		}
+
 		rowCount++
 	}
 	slog.Info(fmt.Sprintf("Wrote %d rows", rowCount))
@@ -237,3 +401,27 @@ func importFileIn(inputZip *zip.ReadCloser, db *sqlite.Conn, filename string) er
 
 	return nil
 }
+
+type originalText struct {
+	column string
+	value  any // As bound to the insert statement
+	text   string
+}
+
+const originalTextSchema = `
+CREATE TABLE IF NOT EXISTS __gtfs2sqlite_original_text (tableName TEXT, rowID INTEGER, columnName TEXT, value, text TEXT);
+CREATE INDEX IF NOT EXISTS __gtfs2sqlite_original_text_idx ON __gtfs2sqlite_original_text (tableName, rowID);
+`
+
+func saveOriginalText(db *sqlite.Conn, table string, rowid int64, original originalText) error {
+	return sqlitex.Exec(db,
+		"INSERT INTO __gtfs2sqlite_original_text (tableName, rowID, columnName, value, text) VALUES (?, ?, ?, ?, ?)",
+		sqlitexNoop, table, rowid, original.column, original.value, original.text)
+}
+
+// forgetOriginalText deletes the original text saved for column of a row whose value has been repaired
+func forgetOriginalText(db *sqlite.Conn, table string, rowid int64, column string) error {
+	return sqlitex.Exec(db,
+		"DELETE FROM __gtfs2sqlite_original_text WHERE tableName = ? AND rowID = ? AND columnName = ?",
+		sqlitexNoop, table, rowid, column)
+}
@@ -1,13 +1,18 @@
 package gtfs2sqlite
 
 import (
+	"archive/zip"
 	"crawshaw.io/sqlite"
 	"crawshaw.io/sqlite/sqlitex"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
 	"os"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestImportsValid(t *testing.T) {
@@ -57,6 +62,509 @@ func TestImportInvalid(t *testing.T) {
 	}
 }
 
+func TestImportsTypedColumns(t *testing.T) {
+	outDir := testTempdir(t)
+	_, err := Import("./sample_data/sample-feed.zip", outDir+"/feed.db", &ImportOpts{ReferenceDate: sampleDate})
+	require.NoError(t, err)
+
+	conn, err := sqlite.OpenConn(outDir+"/feed.db", sqlite.SQLITE_OPEN_READONLY)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	typeOf := func(query string) string {
+		var got string
+		err := sqlitex.Exec(conn, query, func(stmt *sqlite.Stmt) error {
+			got = stmt.ColumnText(0)
+			return nil
+		})
+		require.NoError(t, err)
+		return got
+	}
+	assert.Equal(t, "real", typeOf("SELECT typeof(stop_lat) FROM stops LIMIT 1"))
+	assert.Equal(t, "integer", typeOf("SELECT typeof(stop_sequence) FROM stop_times LIMIT 1"))
+	assert.Equal(t, "integer", typeOf("SELECT typeof(start_date) FROM calendar LIMIT 1"))
+	assert.Equal(t, "text", typeOf("SELECT typeof(block_id) FROM trips WHERE block_id IS NOT NULL LIMIT 1"))
+
+	// 6:00:00 < 10:00:00 only compares correctly as a number
+	assert.Equal(t, "21600", typeOf("SELECT min(start_time) FROM frequencies"))
+	assert.Equal(t, "79200", typeOf("SELECT max(end_time) FROM frequencies"))
+}
+
+func TestImportCreatesIndexes(t *testing.T) {
+	indexes := func(t *testing.T, path string) []string {
+		conn, err := sqlite.OpenConn(path, sqlite.SQLITE_OPEN_READONLY)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		var got []string
+		err = sqlitex.Exec(conn, "SELECT name FROM sqlite_master WHERE type = 'index' AND name NOT LIKE '__gtfs2sqlite%'",
+			func(stmt *sqlite.Stmt) error {
+				got = append(got, stmt.GetText("name"))
+				return nil
+			})
+		require.NoError(t, err)
+		return got
+	}
+
+	t.Run("default", func(t *testing.T) {
+		outDir := testTempdir(t)
+		_, err := Import("./sample_data/sample-feed.zip", outDir+"/feed.db", &ImportOpts{ReferenceDate: sampleDate})
+		require.NoError(t, err)
+
+		got := indexes(t, outDir+"/feed.db")
+		assert.Contains(t, got, "stops_pkey")
+		assert.Contains(t, got, "stop_times_pkey")
+		assert.Contains(t, got, "stop_times_stop_id_idx")
+		assert.Contains(t, got, "stops_zone_id_idx")
+		assert.NotContains(t, got, "stop_times_trip_id_idx") // Covered by stop_times_pkey
+	})
+	t.Run("skip", func(t *testing.T) {
+		outDir := testTempdir(t)
+		_, err := Import("./sample_data/sample-feed.zip", outDir+"/feed.db", &ImportOpts{SkipIndexes: true, ReferenceDate: sampleDate})
+		require.NoError(t, err)
+		assert.Empty(t, indexes(t, outDir+"/feed.db"))
+	})
+}
+
+func TestImportInvalidValue(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon\n" +
+			"FUR_CREEK_RES,Furnace Creek Resort (Demo),36.425288,-117.133162\n" +
+			"BEATTY_AIRPORT,Nye County Airport (Demo),north,-116.784582\n" +
+			"BULLFROG,Bullfrog (Demo),36.88108,-116.81797\n" +
+			"STAGECOACH,Stagecoach Hotel & Casino (Demo),36.915682,-116.751677\n" +
+			"NADAV,North Ave / D Ave N (Demo),36.914893,-116.76821\n" +
+			"NANAA,North Ave / N A Ave (Demo),36.914944,-116.761472\n" +
+			"DADAN,Doing Ave / D Ave N (Demo),36.909489,-116.768242\n" +
+			"EMSI,E Main St / S Irving St (Demo),36.905697,-116.76218\n" +
+			"AMV,Amargosa Valley (Demo),36.641496,-116.40094\n",
+	})
+
+	t.Run("nofix", func(t *testing.T) {
+		outDir := testTempdir(t)
+		issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ReferenceDate: sampleDate})
+		require.ErrorIs(t, err, ErrInvalidInput)
+		require.Len(t, issues, 1)
+		assert.Equal(t, "north in stops.txt is not a valid stop_lat (expected Latitude)", issues[0].Message)
+	})
+	t.Run("ignore", func(t *testing.T) {
+		outDir := testTempdir(t)
+		issues, err := Import(input, outDir+"/imported.db", &ImportOpts{IgnoreInvalid: true, ReferenceDate: sampleDate})
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+
+		// The invalid value is preserved
+		err = Export(outDir+"/imported.db", outDir+"/exported.zip", nil)
+		require.NoError(t, err)
+		assertGTFSEqual(t, input, outDir+"/exported.zip")
+	})
+}
+
+func TestImportMalformedValues(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"agency.txt": "agency_id,agency_name,agency_url,agency_timezone\n" +
+			"DTA,Demo Transit Authority,google.com,America/Las_Vegas\n",
+		"calendar.txt": "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\n" +
+			"FULLW,1,1,1,1,1,1,1,20070101,20101231\n" +
+			"WE,0,0,0,0,0,1,1,20070101,20100231\n",
+	})
+
+	outDir := testTempdir(t)
+	issues, err := Import(input, outDir+"/imported.db", &ImportOpts{IgnoreInvalid: true, ReferenceDate: sampleDate})
+	require.NoError(t, err)
+	require.Len(t, issues, 3)
+	assert.Contains(t, issueMessages(issues), "google.com in agency.txt is not a valid agency_url (expected URL)")
+	assert.Contains(t, issueMessages(issues), "America/Las_Vegas in agency.txt is not a valid agency_timezone (expected Timezone)")
+	assert.Contains(t, issueMessages(issues), "20100231 in calendar.txt is not a valid end_date (expected Date)")
+
+	// Invalid values are preserved
+	err = Export(outDir+"/imported.db", outDir+"/exported.zip", nil)
+	require.NoError(t, err)
+	assertGTFSEqual(t, input, outDir+"/exported.zip")
+}
+
+func TestImportInvalidEnum(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"routes.txt": "route_id,agency_id,route_short_name,route_long_name,route_type\n" +
+			"AB,DTA,10,Airport - Bullfrog,99\n" +
+			"BFC,DTA,20,Bullfrog - Furnace Creek Resort,3\n" +
+			"STBA,DTA,30,Stagecoach - Airport Shuttle,700\n" +
+			"CITY,DTA,40,City,3\n" +
+			"AAMV,DTA,50,Airport - Amargosa Valley,3\n",
+		"frequencies.txt": "trip_id,start_time,end_time,headway_secs,exact_times\n" +
+			"STBA,6:00:00,22:00:00,1800,2\n" +
+			"STBA,22:00:00,23:00:00,1800,x\n",
+	})
+
+	t.Run("nofix", func(t *testing.T) {
+		outDir := testTempdir(t)
+		issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ReferenceDate: sampleDate})
+		require.ErrorIs(t, err, ErrInvalidInput)
+		require.Len(t, issues, 3)
+		assert.Contains(t, strings.Join(issueMessages(issues), "\n"), "99 in routes.txt is not a valid route_type (expected one of 0-7, 11, 12, 100-117")
+		assert.Contains(t, issueMessages(issues), "2 in frequencies.txt is not a valid exact_times (expected one of 0, 1)")
+		assert.Contains(t, issueMessages(issues), "x in frequencies.txt is not a valid exact_times (expected one of 0, 1)")
+	})
+	t.Run("coerce", func(t *testing.T) {
+		outDir := testTempdir(t)
+		issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ForceValid: true, CoerceInvalidEnums: true, ReferenceDate: sampleDate})
+		require.NoError(t, err)
+		// Deleting the route cascades to its 2 trips, their 4 stop times and its fare rule
+		require.Len(t, issues, 10)
+
+		conn, err := sqlite.OpenConn(outDir+"/imported.db", sqlite.SQLITE_OPEN_READONLY)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		var exactTimes []int64
+		err = sqlitex.Exec(conn, "SELECT exact_times FROM frequencies", func(stmt *sqlite.Stmt) error {
+			exactTimes = append(exactTimes, stmt.GetInt64("exact_times"))
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0, 0}, exactTimes)
+
+		// route_type has no default so the route is deleted
+		var routeCount int64
+		err = sqlitex.Exec(conn, "SELECT count(*) AS count FROM routes WHERE route_id = 'AB'", func(stmt *sqlite.Stmt) error {
+			routeCount = stmt.GetInt64("count")
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Zero(t, routeCount)
+	})
+	t.Run("warning", func(t *testing.T) {
+		rules := &Rules{Severities: map[string]Severity{CodeInvalidEnum + ":routes.route_type": SeverityWarning}}
+
+		outDir := testTempdir(t)
+		issues, err := Import(input, outDir+"/imported.db", &ImportOpts{Rules: rules, ReferenceDate: sampleDate})
+		require.ErrorIs(t, err, ErrInvalidInput)
+		require.Len(t, issues, 3)
+
+		// Warnings don't fail the import
+		rules.Disabled = []string{CodeInvalidEnum + ":frequencies.exact_times"}
+		issues, err = Import(input, outDir+"/imported.db", &ImportOpts{Rules: rules, ReferenceDate: sampleDate})
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		assert.Equal(t, SeverityWarning, issues[0].Severity)
+
+		// Warnings aren't fixed
+		issues, err = Import(input, outDir+"/imported.db", &ImportOpts{Rules: rules, ForceValid: true, ReferenceDate: sampleDate})
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		assert.False(t, issues[0].Deleted)
+		conn, err := sqlite.OpenConn(outDir+"/imported.db", sqlite.SQLITE_OPEN_READONLY)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+		var routeCount int64
+		err = sqlitex.Exec(conn, "SELECT count(*) AS count FROM routes WHERE route_id = 'AB'", func(stmt *sqlite.Stmt) error {
+			routeCount = stmt.GetInt64("count")
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), routeCount)
+	})
+}
+
+func TestImportPresenceRules(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-multiagency-feed.zip", map[string]string{
+		"routes.txt": "route_id,agency_id,route_short_name,route_long_name,route_type\n" +
+			"AB,DT2,10,Airport - Bullfrog,3\n" +
+			"BFC,,20,Bullfrog - Furnace Creek Resort,3\n" +
+			"STBA,DTA,30,Stagecoach - Airport Shuttle,3\n" +
+			"CITY,DTA,40,City,3\n" +
+			"AAMV,DT2,50,Airport - Amargosa Valley,3\n",
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station\n" +
+			"FUR_CREEK_RES,Furnace Creek Resort (Demo),36.425288,-117.133162,,\n" +
+			"BEATTY_AIRPORT,Nye County Airport (Demo),36.868446,-116.784582,,\n" +
+			"BULLFROG,Bullfrog (Demo),36.88108,-116.81797,,\n" +
+			"STAGECOACH,Stagecoach Hotel & Casino (Demo),36.915682,-116.751677,,\n" +
+			"NADAV,North Ave / D Ave N (Demo),36.914893,-116.76821,,\n" +
+			"NANAA,North Ave / N A Ave (Demo),36.914944,-116.761472,,\n" +
+			"DADAN,Doing Ave / D Ave N (Demo),36.909489,-116.768242,,\n" +
+			"EMSI,E Main St / S Irving St (Demo),36.905697,-116.76218,,\n" +
+			"AMV,Amargosa Valley (Demo),36.641496,-116.40094,,\n" +
+			"AMV_STATION,Amargosa Valley Station (Demo),36.641496,-116.40094,1,AMV\n" +
+			"AMV_ENTRANCE,Amargosa Valley Station Entrance (Demo),36.641496,-116.40094,2,\n",
+	})
+
+	outDir := testTempdir(t)
+	issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ReferenceDate: sampleDate})
+	require.ErrorIs(t, err, ErrInvalidInput)
+	require.Len(t, issues, 6)
+	assert.Contains(t, issueMessages(issues), "routes.txt is missing agency_id, which is required when agency.txt has multiple agencies")
+	// Neither fare in the sample feed has an agency_id
+	assert.Contains(t, issueMessages(issues), "fare_attributes.txt is missing agency_id, which is required when agency.txt has multiple agencies")
+	assert.Contains(t, issueMessages(issues), "stops.txt has parent_station, which is forbidden when location_type is 1")
+	assert.Contains(t, issueMessages(issues), "stops.txt is missing parent_station, which is required when location_type is 2, 3 or 4")
+	// Without a parent_station the entrance isn't part of a station
+	assert.Contains(t, issueMessages(issues), "AMV_ENTRANCE in stops.txt is unused as no stop times serve it or a stop in the same station")
+}
+
+func TestImportFareAttributesAgency(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"agency.txt": "agency_id,agency_name,agency_url,agency_timezone\n" +
+			"DTA,Demo Transit Authority,http://google.com,America/Los_Angeles\n" +
+			"DT2,Agency Two,http://google.com,America/Los_Angeles\n",
+		"routes.txt": "route_id,agency_id,route_short_name,route_long_name,route_type\n" +
+			"AB,DTA,10,Airport - Bullfrog,3\n" +
+			"BFC,DTA,20,Bullfrog - Furnace Creek Resort,3\n" +
+			"STBA,DTA,30,Stagecoach - Airport Shuttle,3\n" +
+			"CITY,DT2,40,City,3\n" +
+			"AAMV,DT2,50,Airport - Amargosa Valley,3\n",
+		"fare_attributes.txt": "fare_id,price,currency_type,payment_method,transfers,agency_id\n" +
+			"p,1.25,USD,0,0,DTA\n" +
+			"a,5.25,USD,0,0,\n",
+	})
+
+	outDir := testTempdir(t)
+	issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ReferenceDate: sampleDate})
+	require.ErrorIs(t, err, ErrInvalidInput)
+	require.Len(t, issues, 1)
+	assert.Equal(t, CodeConditionallyRequired, issues[0].Code)
+	assert.Equal(t, "fare_attributes", issues[0].Table)
+	assert.Equal(t, "agency_id", issues[0].Column)
+	assert.Equal(t, "a", issues[0].Row["fare_id"])
+}
+
+func TestImportIssueFields(t *testing.T) {
+	t.Run("nofix", func(t *testing.T) {
+		outDir := testTempdir(t)
+		issues, err := Import("./sample_data/invalid-foreign-key.zip", outDir+"/imported.db", &ImportOpts{ReferenceDate: sampleDate})
+		require.ErrorIs(t, err, ErrInvalidInput)
+		require.Len(t, issues, 1)
+
+		issue := issues[0]
+		assert.Equal(t, CodeInvalidForeignID, issue.Code)
+		assert.Equal(t, SeverityError, issue.Severity)
+		assert.Equal(t, "routes", issue.Table)
+		assert.Equal(t, "agency_id", issue.Column)
+		assert.NotZero(t, issue.RowID)
+		assert.Equal(t, "nonexistent_agency", issue.Value)
+		assert.Equal(t, "AB", issue.Row["route_id"])
+		assert.Equal(t, "nonexistent_agency", issue.Row["agency_id"])
+		assert.False(t, issue.Deleted)
+		assert.Equal(t, "nonexistent_agency in routes.txt is not a valid agency_id", issue.Message)
+		assert.Contains(t, issue.String(), "[agency_id: nonexistent_agency, route_id: AB, ")
+	})
+	t.Run("fix", func(t *testing.T) {
+		outDir := testTempdir(t)
+		issues, err := Import("./sample_data/invalid-foreign-key.zip", outDir+"/imported.db", &ImportOpts{ForceValid: true, ReferenceDate: sampleDate})
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		// With only one agency the route doesn't need an agency_id
+		assert.Equal(t, RepairNullify, issues[0].Repair)
+		assert.False(t, issues[0].Deleted)
+	})
+}
+
+func TestImportDuplicatePrimaryKey(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"calendar_dates.txt": "service_id,date,exception_type\n" +
+			"FULLW,20070604,2\n" +
+			"FULLW,20070604,2\n" +
+			"FULLW,20070604,1\n",
+		// NULL columns in the primary key compare equal
+		"transfers.txt": "from_stop_id,to_stop_id,from_trip_id,transfer_type\n" +
+			"BULLFROG,BULLFROG,,2\n" +
+			"BULLFROG,BULLFROG,,0\n" +
+			"BULLFROG,BULLFROG,AB1,2\n",
+	})
+
+	t.Run("nofix", func(t *testing.T) {
+		outDir := testTempdir(t)
+		issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ReferenceDate: sampleDate})
+		require.ErrorIs(t, err, ErrInvalidInput)
+		require.Len(t, issues, 2)
+	})
+	t.Run("rules", func(t *testing.T) {
+		// The key of calendar_dates is two columns, so the issue can only be configured for the table
+		rules, err := ParseRules([]byte(`{"severities": {"duplicate_primary_key:calendar_dates": "warning"}}`))
+		require.NoError(t, err)
+		issues, err := Validate(input, &ValidateOpts{Rules: rules, ReferenceDate: sampleDate})
+		require.ErrorIs(t, err, ErrInvalidInput)
+		severities := make(map[string]Severity)
+		for _, issue := range issues {
+			severities[issue.Table+" "+issue.Column] = issue.Severity
+		}
+		assert.Equal(t, map[string]Severity{
+			"calendar_dates service_id,date": SeverityWarning,
+			"transfers from_stop_id,to_stop_id,from_trip_id,to_trip_id,from_route_id,to_route_id": SeverityError,
+		}, severities)
+	})
+	t.Run("fix", func(t *testing.T) {
+		outDir := testTempdir(t)
+		issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ForceValid: true, ReferenceDate: sampleDate})
+		require.NoError(t, err)
+		require.Len(t, issues, 2)
+
+		conn, err := sqlite.OpenConn(outDir+"/imported.db", sqlite.SQLITE_OPEN_READONLY)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		var remaining []string
+		err = sqlitex.Exec(conn, "SELECT exception_type FROM calendar_dates", func(stmt *sqlite.Stmt) error {
+			remaining = append(remaining, stmt.GetText("exception_type"))
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2"}, remaining)
+
+		remaining = nil
+		err = sqlitex.Exec(conn, "SELECT transfer_type FROM transfers", func(stmt *sqlite.Stmt) error {
+			remaining = append(remaining, stmt.GetText("transfer_type"))
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2", "2"}, remaining)
+
+		var unique bool
+		err = sqlitex.Exec(conn, "SELECT \"unique\" FROM pragma_index_list('calendar_dates') WHERE name = 'calendar_dates_pkey'",
+			func(stmt *sqlite.Stmt) error {
+				unique = stmt.ColumnInt(0) == 1
+				return nil
+			})
+		require.NoError(t, err)
+		assert.True(t, unique)
+	})
+}
+
+func TestImportMissingRequired(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"trips.txt": "route_id,service_id,trip_id,trip_headsign,direction_id,block_id,shape_id\n" +
+			"AB,FULLW,AB1,to Bullfrog,0,1,\n" +
+			",FULLW,AB2,to Airport,1,2,\n" +
+			"STBA,FULLW,STBA,Shuttle,,,\n" +
+			"CITY,FULLW,CITY1,,0,,\n" +
+			"CITY,FULLW,CITY2,,1,,\n" +
+			"BFC,FULLW,BFC1,to Furnace Creek Resort,0,1,\n" +
+			"BFC,FULLW,BFC2,to Bullfrog,1,2,\n" +
+			"AAMV,WE,AAMV1,to Amargosa Valley,0,,\n" +
+			"AAMV,WE,AAMV2,to Airport,1,,\n" +
+			"AAMV,WE,AAMV3,to Amargosa Valley,0,,\n" +
+			"AAMV,WE,AAMV4,to Airport,1,,\n",
+		"levels.txt": "level_id,level_name\n" +
+			"L1,Ground\n",
+	})
+
+	t.Run("nofix", func(t *testing.T) {
+		outDir := testTempdir(t)
+		issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ReferenceDate: sampleDate})
+		require.ErrorIs(t, err, ErrInvalidInput)
+		require.Len(t, issues, 3)
+		assert.Contains(t, issueMessages(issues), "levels.txt is missing required column level_index")
+		assert.Contains(t, issueMessages(issues), "trips.txt is missing required route_id")
+		// No stops are on the level
+		assert.Contains(t, issueMessages(issues), "L1 in levels.txt is unused as no stops are on it")
+	})
+	t.Run("fix", func(t *testing.T) {
+		outDir := testTempdir(t)
+		issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ForceValid: true, ReferenceDate: sampleDate})
+		require.NoError(t, err)
+		// Along with the trip's 2 stop times
+		require.Len(t, issues, 5)
+
+		conn, err := sqlite.OpenConn(outDir+"/imported.db", sqlite.SQLITE_OPEN_READONLY)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		// Removing the trip cascades to its stop_times
+		var count int64
+		err = sqlitex.Exec(conn, "SELECT count(*) AS count FROM stop_times WHERE trip_id = 'AB2'", func(stmt *sqlite.Stmt) error {
+			count = stmt.GetInt64("count")
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+}
+
+func TestImportEmptyTransfers(t *testing.T) {
+	// An empty transfers means unlimited transfers
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"fare_attributes.txt": "fare_id,price,currency_type,payment_method,transfers,transfer_duration\n" +
+			"p,1.25,USD,0,,\n" +
+			"a,5.25,USD,0,0,\n",
+	})
+
+	outDir := testTempdir(t)
+	issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ReferenceDate: sampleDate})
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	// The column is still required
+	input = testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"fare_attributes.txt": "fare_id,price,currency_type,payment_method,transfer_duration\n" +
+			"p,1.25,USD,0,\n" +
+			"a,5.25,USD,0,\n",
+	})
+	issues, err = Import(input, outDir+"/missing.db", &ImportOpts{ReferenceDate: sampleDate})
+	require.ErrorIs(t, err, ErrInvalidInput)
+	assert.Equal(t, []string{"fare_attributes.txt is missing required column transfers"}, issueMessages(issues))
+}
+
+func issueMessages(issues []ValidationIssue) []string {
+	var out []string
+	for _, issue := range issues {
+		out = append(out, issue.Message)
+	}
+	return out
+}
+
+// sampleDate is a date the sample feeds, which run from 2007 to 2010, are in effect on
+var sampleDate = time.Date(2008, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// deleteInvalidForeignIDs makes ForceValid delete rows with invalid foreign IDs rather than clearing optional ones
+var deleteInvalidForeignIDs = &Rules{Repairs: map[string][]Repair{CodeInvalidForeignID: {RepairDelete}}}
+
+// testFeed writes a copy of the feed at basePath with the given files replaced
+func testFeed(t *testing.T, basePath string, files map[string]string) string {
+	t.Helper()
+	return rewriteTestFeed(t, basePath, files, nil)
+}
+
+// testFeedWithout copies the feed at basePath leaving out the named files
+func testFeedWithout(t *testing.T, basePath string, names ...string) string {
+	t.Helper()
+	return rewriteTestFeed(t, basePath, nil, names)
+}
+
+func rewriteTestFeed(t *testing.T, basePath string, files map[string]string, without []string) string {
+	t.Helper()
+
+	base, err := zip.OpenReader(basePath)
+	require.NoError(t, err)
+	defer func() { _ = base.Close() }()
+
+	outPath := testTempdir(t) + "/feed.zip"
+	outF, err := os.Create(outPath)
+	require.NoError(t, err)
+	defer func() { _ = outF.Close() }()
+	out := zip.NewWriter(outF)
+
+	for _, entry := range base.File {
+		if _, ok := files[entry.Name]; ok || slices.Contains(without, entry.Name) {
+			continue
+		}
+		inF, err := entry.Open()
+		require.NoError(t, err)
+		outEntry, err := out.Create(entry.Name)
+		require.NoError(t, err)
+		_, err = io.Copy(outEntry, inF)
+		require.NoError(t, err)
+		_ = inF.Close()
+	}
+	for name, contents := range files {
+		outEntry, err := out.Create(name)
+		require.NoError(t, err)
+		_, err = outEntry.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, out.Close())
+	return outPath
+}
+
 func testTempdir(t *testing.T) string {
 	dir, err := os.MkdirTemp("", "")
 	require.NoError(t, err)
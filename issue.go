@@ -0,0 +1,145 @@
+package gtfs2sqlite
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Codes identifying the check that found a ValidationIssue. These are stable across versions.
+const (
+	CodeDuplicatePrimaryKey    = "duplicate_primary_key"
+	CodeMissingRequiredColumn  = "missing_required_column"
+	CodeMissingRequiredValue   = "missing_required_value"
+	CodeConditionallyRequired  = "conditionally_required"
+	CodeConditionallyForbidden = "conditionally_forbidden"
+	CodeInvalidValue           = "invalid_value"
+	CodeInvalidEnum            = "invalid_enum"
+	CodeInvalidForeignID       = "invalid_foreign_id"
+
+	CodeArrivalAfterDeparture   = "arrival_after_departure"
+	CodeDecreasingStopTime      = "decreasing_stop_time"
+	CodeDecreasingShapeDistance = "decreasing_shape_distance"
+	CodeRepeatedStop            = "repeated_stop"
+	CodeTooFewStopTimes         = "too_few_stop_times"
+	CodeImplausibleSpeed        = "implausible_speed"
+
+	CodeInvalidDateRange   = "invalid_date_range"
+	CodeServiceWithoutDays = "service_without_days"
+	CodeRedundantException = "redundant_exception"
+	CodeNeverActiveService = "never_active_service"
+	CodeFeedExpired        = "feed_expired"
+	CodeFeedNotYetActive   = "feed_not_yet_active"
+
+	CodeInvalidTranslationField = "invalid_translation_field"
+	CodeOrphanedTranslation     = "orphaned_translation"
+
+	CodeUnusedEntity = "unused_entity"
+
+	CodeInvalidParentStation   = "invalid_parent_station"
+	CodePathwayBetweenStations = "pathway_between_stations"
+	CodeInconsistentPathway    = "inconsistent_pathway"
+	CodeUnreachablePlatform    = "unreachable_platform"
+	CodeEntranceWithoutPath    = "entrance_without_path"
+
+	CodeInconsistentTimezone = "inconsistent_timezone"
+	CodeStopOutsideTimezone  = "stop_outside_timezone"
+
+	CodeOverlappingBlockTrips = "overlapping_block_trips"
+	CodeBlockWithoutLayover   = "block_without_layover"
+)
+
+// defaultSeverities lists every code with the severity of its issues unless overridden by Rules
+var defaultSeverities = map[string]Severity{
+	CodeDuplicatePrimaryKey:    SeverityError,
+	CodeMissingRequiredColumn:  SeverityError,
+	CodeMissingRequiredValue:   SeverityError,
+	CodeConditionallyRequired:  SeverityError,
+	CodeConditionallyForbidden: SeverityError,
+	CodeInvalidValue:           SeverityError,
+	CodeInvalidEnum:            SeverityError,
+	CodeInvalidForeignID:       SeverityError,
+
+	CodeArrivalAfterDeparture:   SeverityError,
+	CodeDecreasingStopTime:      SeverityError,
+	CodeDecreasingShapeDistance: SeverityError,
+	CodeRepeatedStop:            SeverityWarning,
+	CodeTooFewStopTimes:         SeverityWarning,
+	CodeImplausibleSpeed:        SeverityWarning,
+
+	CodeInvalidDateRange:   SeverityError,
+	CodeServiceWithoutDays: SeverityWarning,
+	CodeRedundantException: SeverityWarning,
+	CodeNeverActiveService: SeverityWarning,
+	CodeFeedExpired:        SeverityWarning,
+	CodeFeedNotYetActive:   SeverityWarning,
+
+	CodeInvalidTranslationField: SeverityError,
+	CodeOrphanedTranslation:     SeverityError,
+
+	CodeUnusedEntity: SeverityWarning,
+
+	CodeInvalidParentStation:   SeverityError,
+	CodePathwayBetweenStations: SeverityError,
+	CodeInconsistentPathway:    SeverityWarning,
+	CodeUnreachablePlatform:    SeverityWarning,
+	CodeEntranceWithoutPath:    SeverityWarning,
+
+	CodeInconsistentTimezone: SeverityError,
+	CodeStopOutsideTimezone:  SeverityWarning,
+
+	CodeOverlappingBlockTrips: SeverityError,
+	CodeBlockWithoutLayover:   SeverityWarning,
+}
+
+type ValidationIssue struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// Table is the name of the file without the .txt extension
+	Table  string `json:"table"`
+	Column string `json:"column,omitempty"`
+	// RowID is the SQLite rowid of the offending row, or zero if the issue isn't about a specific row
+	RowID int64  `json:"rowid,omitempty"`
+	Value string `json:"value,omitempty"`
+	// Row holds the non-empty fields of the offending row
+	Row map[string]string `json:"row,omitempty"`
+	// Suggestions are existing IDs close to an invalid foreign ID. IDs that only differ in case or surrounding
+	// whitespace are suggested alone.
+	Suggestions []string `json:"suggestions,omitempty"`
+	// Repair is how ForceValid fixed the issue, or would have in a dry run
+	Repair Repair `json:"repair,omitempty"`
+	// Deleted is whether the repair was to delete the row
+	Deleted bool `json:"deleted,omitempty"`
+	// Pass is the number of times ForceValid had deleted rows and re-validated before finding the issue. Issues are
+	// only returned from passes after the first if they were repaired.
+	Pass int `json:"pass,omitempty"`
+}
+
+func (i ValidationIssue) String() string {
+	if len(i.Row) == 0 {
+		return i.Message
+	}
+	return fmt.Sprintf("%s [%s]", i.Message, formatRow(i.Row))
+}
+
+func formatRow(row map[string]string) string {
+	var columns []string
+	for column := range row {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+
+	var out []string
+	for _, column := range columns {
+		out = append(out, fmt.Sprintf("%s: %s", column, row[column]))
+	}
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,148 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// Databases imported by versions before numeric, date and time columns were typed store every value as TEXT. They
+// are migrated by recreating their tables with typed columns, parsing the values as Import does.
+
+// legacyTables returns the tables with a column whose declared type doesn't match the schema
+func legacyTables(db *sqlite.Conn) ([]string, error) {
+	var tables []string
+	err := sqlitex.Exec(db, "SELECT name FROM sqlite_master WHERE type = 'table'", func(stmt *sqlite.Stmt) error {
+		if _, ok := gtfsSchema[stmt.GetText("name")]; ok {
+			tables = append(tables, stmt.GetText("name"))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var legacy []string
+	for _, table := range tables {
+		mismatched := false
+		err := sqlitex.Exec(db, "SELECT name, type FROM pragma_table_info(?)", func(stmt *sqlite.Stmt) error {
+			column, ok := gtfsSchema[table].Columns[stmt.GetText("name")]
+			if ok && !strings.EqualFold(stmt.GetText("type"), column.kind().sqlType()) {
+				mismatched = true
+			}
+			return nil
+		}, table)
+		if err != nil {
+			return nil, err
+		}
+		if mismatched {
+			legacy = append(legacy, table)
+		}
+	}
+	slices.Sort(legacy)
+	return legacy, nil
+}
+
+// migrateLegacyTables recreates tables with typed columns, keeping rowids, unknown columns and indexes. Values that
+// can't be parsed are kept as text for the validator to report.
+func migrateLegacyTables(db *sqlite.Conn, tables []string) (err error) {
+	defer sqlitex.Save(db)(&err)
+
+	if err := sqlitex.ExecScript(db, originalTextSchema); err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if err := migrateLegacyTable(db, table); err != nil {
+			return fmt.Errorf("migrate %s: %w", table, err)
+		}
+	}
+	slog.Info(fmt.Sprintf("Migrated %d table(s) from an older version", len(tables)))
+	return nil
+}
+
+func migrateLegacyTable(db *sqlite.Conn, table string) error {
+	var indexes []string
+	err := sqlitex.Exec(db, "SELECT sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL",
+		func(stmt *sqlite.Stmt) error {
+			indexes = append(indexes, stmt.GetText("sql"))
+			return nil
+		}, table)
+	if err != nil {
+		return err
+	}
+
+	columns, err := tableColumns(db, table)
+	if err != nil {
+		return err
+	}
+
+	legacy := "__gtfs2sqlite_legacy_" + table
+	if err := sqlitex.ExecTransient(db, fmt.Sprintf("ALTER TABLE %s RENAME TO %s", table, legacy), sqlitexNoop); err != nil {
+		return err
+	}
+	if err := createTable(db, table, gtfsSchema[table]); err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if _, ok := gtfsSchema[table].Columns[column]; ok {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE %s ADD %s TEXT", table, column)
+		if err := sqlitex.ExecTransient(db, query, sqlitexNoop); err != nil {
+			return err
+		}
+	}
+
+	var params []string
+	for range columns {
+		params = append(params, "?")
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (rowid, %s) VALUES (?, %s)",
+		table, strings.Join(columns, ", "), strings.Join(params, ", "))
+	query := fmt.Sprintf("SELECT rowid, %s FROM %s", strings.Join(columns, ", "), legacy)
+	err = sqlitex.Exec(db, query, func(stmt *sqlite.Stmt) error {
+		rowid := stmt.ColumnInt64(0)
+		args := []any{rowid}
+		for i, column := range columns {
+			value := columnValue(stmt, i+1)
+			text, isText := value.(string)
+			kind := gtfsSchema[table].Columns[column].kind()
+			if !isText || kind == textKind {
+				args = append(args, value)
+				continue
+			}
+
+			parsed, ok := parseValue(kind, text)
+			if !ok {
+				args = append(args, text)
+				if err := saveOriginalText(db, table, rowid, originalText{column: column, value: text, text: text}); err != nil {
+					return err
+				}
+				continue
+			}
+			args = append(args, parsed)
+			if formatValue(kind, parsed) != text {
+				if err := saveOriginalText(db, table, rowid, originalText{column: column, value: parsed, text: text}); err != nil {
+					return err
+				}
+			}
+		}
+		return sqlitex.Exec(db, insert, sqlitexNoop, args...)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := sqlitex.ExecTransient(db, "DROP TABLE "+legacy, sqlitexNoop); err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if err := sqlitex.ExecTransient(db, index, sqlitexNoop); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package gtfs2sqlite
+
+import (
+	"archive/zip"
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"encoding/csv"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+)
+
+// importLegacy imports a feed the way versions before typed columns did, storing every value as TEXT
+func importLegacy(t *testing.T, inputPath, outputPath string) {
+	t.Helper()
+
+	db, err := sqlite.OpenConn(outputPath, 0)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	for table, schema := range gtfsSchema {
+		var columns []string
+		for column := range schema.Columns {
+			columns = append(columns, column+" TEXT")
+		}
+		query := fmt.Sprintf("CREATE TABLE %s (%s)", table, strings.Join(columns, ", "))
+		require.NoError(t, sqlitex.ExecTransient(db, query, sqlitexNoop))
+	}
+
+	inputZip, err := zip.OpenReader(inputPath)
+	require.NoError(t, err)
+	defer func() { _ = inputZip.Close() }()
+	for _, file := range inputZip.File {
+		if !strings.HasSuffix(file.Name, ".txt") {
+			continue
+		}
+		table := strings.TrimSuffix(file.Name, ".txt")
+		f, err := file.Open()
+		require.NoError(t, err)
+		r := csv.NewReader(f)
+		r.FieldsPerRecord = -1
+		records, err := r.ReadAll()
+		require.NoError(t, err)
+		_ = f.Close()
+
+		header := records[0]
+		for _, column := range header {
+			if _, ok := gtfsSchema[table].Columns[column]; !ok {
+				query := fmt.Sprintf("ALTER TABLE %s ADD %s TEXT", table, column)
+				require.NoError(t, sqlitex.ExecTransient(db, query, sqlitexNoop))
+			}
+		}
+		params := strings.TrimSuffix(strings.Repeat("?, ", len(header)), ", ")
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+			table, strings.Join(header, ", "), params)
+		if len(records) == 1 {
+			err := sqlitex.Exec(db, "CREATE TABLE IF NOT EXISTS __gtfs2sqlite_empty_files (tableName TEXT)", sqlitexNoop)
+			require.NoError(t, err)
+			err = sqlitex.Exec(db, "INSERT INTO __gtfs2sqlite_empty_files (tableName) VALUES (?)", sqlitexNoop, table)
+			require.NoError(t, err)
+		}
+		for _, record := range records[1:] {
+			args := make([]any, len(header))
+			for i, value := range record {
+				if value != "" {
+					args[i] = value
+				}
+			}
+			require.NoError(t, sqlitex.Exec(db, query, sqlitexNoop, args...))
+		}
+	}
+}
+
+func TestValidateLegacyDatabase(t *testing.T) {
+	outDir := testTempdir(t)
+	importLegacy(t, "./sample_data/sample-feed.zip", outDir+"/legacy.db")
+
+	issues, err := Validate(outDir+"/legacy.db", &ValidateOpts{ReferenceDate: sampleDate})
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	// The input isn't migrated
+	conn, err := sqlite.OpenConn(outDir+"/legacy.db", sqlite.SQLITE_OPEN_READONLY)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	legacy, err := legacyTables(conn)
+	require.NoError(t, err)
+	assert.Contains(t, legacy, "stop_times")
+}
+
+func TestClipLegacyDatabase(t *testing.T) {
+	outDir := testTempdir(t)
+	importLegacy(t, "./sample_data/sample-multiagency-feed.zip", outDir+"/legacy.db")
+
+	feature, err := os.ReadFile("./sample_data/ne_beatty.json")
+	require.NoError(t, err)
+
+	err = Clip(outDir+"/legacy.db", outDir+"/clipped.db", string(feature))
+	require.NoError(t, err)
+
+	err = Export(outDir+"/clipped.db", outDir+"/exported.zip", nil)
+	require.NoError(t, err, "export")
+
+	assertGTFSEqual(t, "./sample_data/sample-multiagency-feed-clipped-to-ne_beatty.zip", outDir+"/exported.zip")
+}
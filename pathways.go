@@ -0,0 +1,337 @@
+package gtfs2sqlite
+
+import (
+	"cmp"
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"fmt"
+	"slices"
+)
+
+// Values of stops.location_type
+const (
+	locationStop         = 0 // A stop or platform. Empty is the same.
+	locationStation      = 1
+	locationEntrance     = 2
+	locationGenericNode  = 3
+	locationBoardingArea = 4
+)
+
+// parentLocationTypes is the location_type the parent_station of each location_type must have
+var parentLocationTypes = map[int64]int64{
+	locationStop:         locationStation,
+	locationEntrance:     locationStation,
+	locationGenericNode:  locationStation,
+	locationBoardingArea: locationStop,
+}
+
+var locationTypeNames = map[int64]string{
+	locationStop:         "a stop or platform",
+	locationStation:      "a station",
+	locationEntrance:     "an entrance",
+	locationGenericNode:  "a generic node",
+	locationBoardingArea: "a boarding area",
+}
+
+// Values of pathways.pathway_mode that can only be used in one direction
+const (
+	pathwayEscalator = 4
+	pathwayExitGate  = 7
+)
+
+type stationStop struct {
+	rowid        int64
+	row          map[string]string
+	locationType int64
+	parent       string
+}
+
+// readStationStops reads the stops in stations
+func (v *validator) readStationStops() (map[string]stationStop, error) {
+	stops := make(map[string]stationStop)
+	err := sqlitex.Exec(v.db, "SELECT rowid, * FROM stops WHERE stop_id IS NOT NULL", func(stmt *sqlite.Stmt) error {
+		stops[stmt.GetText("stop_id")] = stationStop{
+			rowid:        stmt.GetInt64("rowid"),
+			row:          rowValues("stops", stmt),
+			locationType: stmt.GetInt64("location_type"),
+			parent:       stmt.GetText("parent_station"),
+		}
+		return nil
+	})
+	return stops, err
+}
+
+// station returns the station a stop is part of, or "" if it isn't part of one
+func station(stops map[string]stationStop, id string) string {
+	stop := stops[id]
+	if stop.locationType == locationBoardingArea {
+		return stops[stop.parent].parent
+	}
+	if stop.locationType == locationStation {
+		return id
+	}
+	return stop.parent
+}
+
+// validateParentStations checks the parent_station of each stop has the right location_type. Boarding areas belong
+// to platforms, and everything else to stations.
+func (v *validator) validateParentStations() error {
+	query := `
+SELECT stops.rowid AS rowid, stops.*, parent.location_type AS __gtfs2sqlite_parent_type
+FROM stops JOIN stops AS parent ON parent.stop_id = stops.parent_station
+WHERE typeof(stops.location_type) IN ('integer', 'null') AND typeof(parent.location_type) IN ('integer', 'null')`
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		locationType := stmt.GetInt64("location_type")
+		parentType := stmt.GetInt64("__gtfs2sqlite_parent_type")
+		expected, ok := parentLocationTypes[locationType]
+		if !ok || parentType == expected {
+			return nil
+		}
+
+		row := rowValues("stops", stmt)
+		v.reject(ValidationIssue{
+			Code: CodeInvalidParentStation,
+			Message: fmt.Sprintf("parent_station %s in stops.txt is %s, but the parent of %s must be %s",
+				row["parent_station"], locationTypeNames[parentType], locationTypeNames[locationType],
+				locationTypeNames[expected]),
+			Table:  "stops",
+			Column: "parent_station",
+			RowID:  stmt.GetInt64("rowid"),
+			Value:  row["parent_station"],
+			Row:    row,
+		})
+		return nil
+	})
+}
+
+// validatePathways runs the checks of pathways between stops, sharing the stops they read. Feeds without pathways
+// are skipped without reading the stops.
+func (v *validator) validatePathways() error {
+	hasPathways := false
+	err := sqlitex.Exec(v.db, "SELECT 1 FROM pathways LIMIT 1", func(stmt *sqlite.Stmt) error {
+		hasPathways = true
+		return nil
+	})
+	if err != nil || !hasPathways {
+		return err
+	}
+
+	stops, err := v.readStationStops()
+	if err != nil {
+		return err
+	}
+	if err := v.validatePathwayStations(stops); err != nil {
+		return err
+	}
+	return v.validateStationGraphs(stops)
+}
+
+// validatePathwayStations checks pathways connect stops of the same station
+func (v *validator) validatePathwayStations(stops map[string]stationStop) error {
+	return sqlitex.Exec(v.db, "SELECT rowid, * FROM pathways", func(stmt *sqlite.Stmt) error {
+		row := rowValues("pathways", stmt)
+		from, to := row["from_stop_id"], row["to_stop_id"]
+		fromStation, toStation := station(stops, from), station(stops, to)
+		// Unknown stops are invalid foreign IDs
+		if fromStation == "" || toStation == "" || fromStation == toStation {
+			return nil
+		}
+
+		v.reject(ValidationIssue{
+			Code: CodePathwayBetweenStations,
+			Message: fmt.Sprintf("%s in pathways.txt connects %s in station %s to %s in station %s",
+				row["pathway_id"], from, fromStation, to, toStation),
+			Table:  "pathways",
+			Column: "to_stop_id",
+			RowID:  stmt.GetInt64("rowid"),
+			Value:  to,
+			Row:    row,
+		})
+		return nil
+	})
+}
+
+// validatePathwayDirections checks escalators and exit gates aren't bidirectional, and that a bidirectional pathway
+// doesn't overlap another pathway between the same stops with a different traversal_time
+func (v *validator) validatePathwayDirections() error {
+	query := fmt.Sprintf("SELECT rowid, * FROM pathways WHERE is_bidirectional = 1 AND pathway_mode IN (%d, %d)",
+		pathwayEscalator, pathwayExitGate)
+	err := sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		row := rowValues("pathways", stmt)
+		mode := "an escalator"
+		if stmt.GetInt64("pathway_mode") == pathwayExitGate {
+			mode = "an exit gate"
+		}
+		v.reject(ValidationIssue{
+			Code:    CodeInconsistentPathway,
+			Message: fmt.Sprintf("%s in pathways.txt is bidirectional but is %s", row["pathway_id"], mode),
+			Table:   "pathways",
+			Column:  "is_bidirectional",
+			RowID:   stmt.GetInt64("rowid"),
+			Value:   row["is_bidirectional"],
+			Row:     row,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	query = `
+SELECT pathways.rowid AS rowid, pathways.*, other.pathway_id AS __gtfs2sqlite_other,
+	other.traversal_time AS __gtfs2sqlite_other_time
+FROM pathways JOIN pathways AS other ON other.rowid != pathways.rowid AND (
+	(other.from_stop_id = pathways.from_stop_id AND other.to_stop_id = pathways.to_stop_id) OR
+	(other.from_stop_id = pathways.to_stop_id AND other.to_stop_id = pathways.from_stop_id)
+)
+WHERE pathways.is_bidirectional = 1 AND other.pathway_mode IS pathways.pathway_mode
+	AND other.traversal_time IS NOT pathways.traversal_time`
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		row := rowValues("pathways", stmt)
+		v.reject(ValidationIssue{
+			Code: CodeInconsistentPathway,
+			Message: fmt.Sprintf("%s in pathways.txt is bidirectional with traversal_time %s, but %s between the same stops has %s",
+				row["pathway_id"], formatOptional(row["traversal_time"]), stmt.GetText("__gtfs2sqlite_other"),
+				formatOptional(stmt.GetText("__gtfs2sqlite_other_time"))),
+			Table:  "pathways",
+			Column: "traversal_time",
+			RowID:  stmt.GetInt64("rowid"),
+			Value:  row["traversal_time"],
+			Row:    row,
+		})
+		return nil
+	})
+}
+
+func formatOptional(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+// validateStationGraphs checks that in stations with pathways every platform can be reached from an entrance and
+// every entrance leads to a platform. Reaching one of a platform's boarding areas counts as reaching the platform.
+func (v *validator) validateStationGraphs(stops map[string]stationStop) error {
+	// Edges within each station
+	edges := make(map[string]map[string][]string)
+	reverseEdges := make(map[string]map[string][]string)
+	addEdge := func(from, to string) {
+		s := station(stops, from)
+		if edges[s] == nil {
+			edges[s] = make(map[string][]string)
+			reverseEdges[s] = make(map[string][]string)
+		}
+		edges[s][from] = append(edges[s][from], to)
+		reverseEdges[s][to] = append(reverseEdges[s][to], from)
+	}
+	err := sqlitex.Exec(v.db, "SELECT * FROM pathways", func(stmt *sqlite.Stmt) error {
+		from, to := stmt.GetText("from_stop_id"), stmt.GetText("to_stop_id")
+		if _, ok := stops[from]; !ok {
+			return nil
+		}
+		if _, ok := stops[to]; !ok {
+			return nil
+		}
+		// Pathways between stations are reported by validatePathwayStations
+		if station(stops, from) == "" || station(stops, from) != station(stops, to) {
+			return nil
+		}
+		addEdge(from, to)
+		if stmt.GetInt64("is_bidirectional") == 1 {
+			addEdge(to, from)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// platform returns the platform a stop is or is a boarding area of, or "" if it is neither
+	platform := func(id string) string {
+		switch stops[id].locationType {
+		case locationStop:
+			return id
+		case locationBoardingArea:
+			return stops[id].parent
+		default:
+			return ""
+		}
+	}
+
+	var ids []string
+	boardingAreas := make(map[string][]string)
+	for id, stop := range stops {
+		ids = append(ids, id)
+		if stop.locationType == locationBoardingArea {
+			boardingAreas[stop.parent] = append(boardingAreas[stop.parent], id)
+		}
+	}
+	slices.SortFunc(ids, func(a, b string) int { return cmp.Compare(stops[a].rowid, stops[b].rowid) })
+
+	for _, id := range ids {
+		stop := stops[id]
+		s := station(stops, id)
+		if edges[s] == nil {
+			continue
+		}
+
+		switch {
+		case stop.locationType == locationStop && stop.parent != "":
+			var entrances []string
+			for other := range reachable(reverseEdges[s], append([]string{id}, boardingAreas[id]...)) {
+				if stops[other].locationType == locationEntrance {
+					entrances = append(entrances, other)
+				}
+			}
+			if len(entrances) == 0 {
+				v.reject(ValidationIssue{
+					Code:    CodeUnreachablePlatform,
+					Message: fmt.Sprintf("%s in stops.txt is a platform of station %s that no entrance has a pathway to", id, s),
+					Table:   "stops",
+					Column:  "stop_id",
+					RowID:   stop.rowid,
+					Value:   id,
+					Row:     stop.row,
+				})
+			}
+		case stop.locationType == locationEntrance:
+			leadsToPlatform := false
+			for other := range reachable(edges[s], []string{id}) {
+				if platform(other) != "" {
+					leadsToPlatform = true
+					break
+				}
+			}
+			if !leadsToPlatform {
+				v.reject(ValidationIssue{
+					Code:    CodeEntranceWithoutPath,
+					Message: fmt.Sprintf("%s in stops.txt is an entrance of station %s with no pathway to any platform", id, s),
+					Table:   "stops",
+					Column:  "stop_id",
+					RowID:   stop.rowid,
+					Value:   id,
+					Row:     stop.row,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// reachable returns the nodes reachable from start by following edges, including start
+func reachable(edges map[string][]string, start []string) map[string]bool {
+	seen := make(map[string]bool)
+	queue := slices.Clone(start)
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if seen[node] {
+			continue
+		}
+		seen[node] = true
+		queue = append(queue, edges[node]...)
+	}
+	return seen
+}
@@ -0,0 +1,105 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"slices"
+	"testing"
+)
+
+func TestValidatePathways(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station\n" +
+			"FUR_CREEK_RES,Furnace Creek Resort (Demo),36.425288,-117.133162,,\n" +
+			"BEATTY_AIRPORT_STATION,Nye County Airport Station (Demo),36.868446,-116.784582,1,\n" +
+			"BEATTY_AIRPORT,Nye County Airport (Demo),36.868446,-116.784582,,BEATTY_AIRPORT_STATION\n" +
+			"P2,Nye County Airport Platform 2 (Demo),36.868446,-116.784582,,BEATTY_AIRPORT_STATION\n" +
+			"E1,Nye County Airport Entrance (Demo),36.868446,-116.784582,2,BEATTY_AIRPORT_STATION\n" +
+			"E2,Nye County Airport Exit (Demo),36.868446,-116.784582,2,BEATTY_AIRPORT_STATION\n" +
+			"E3,Nye County Airport Car Park (Demo),36.868446,-116.784582,2,BEATTY_AIRPORT_STATION\n" +
+			"N1,,,,3,BEATTY_AIRPORT_STATION\n" +
+			"BA1,,,,4,BEATTY_AIRPORT\n" +
+			"BA_BAD,,,,4,N1\n" +
+			"P3,Nye County Airport Platform 3 (Demo),36.868446,-116.784582,,E1\n" +
+			"BULLFROG,Bullfrog (Demo),36.88108,-116.81797,,\n" +
+			"STAGECOACH_STATION,Stagecoach Hotel & Casino Station (Demo),36.915682,-116.751677,1,\n" +
+			"STAGECOACH,Stagecoach Hotel & Casino (Demo),36.915682,-116.751677,,STAGECOACH_STATION\n" +
+			"NADAV,North Ave / D Ave N (Demo),36.914893,-116.76821,,\n" +
+			"NANAA,North Ave / N A Ave (Demo),36.914944,-116.761472,,\n" +
+			"DADAN,Doing Ave / D Ave N (Demo),36.909489,-116.768242,,\n" +
+			"EMSI,E Main St / S Irving St (Demo),36.905697,-116.76218,,\n" +
+			"AMV,Amargosa Valley (Demo),36.641496,-116.40094,,\n",
+		"pathways.txt": "pathway_id,from_stop_id,to_stop_id,pathway_mode,is_bidirectional,traversal_time\n" +
+			"W1,E1,N1,1,1,30\n" +
+			// The platform is reached through its boarding area
+			"W2,N1,BA1,1,1,20\n" +
+			"W3,BA1,N1,1,0,25\n" +
+			"X1,N1,E2,7,1,10\n" +
+			// E3 can only be entered from E1
+			"W4,E1,E3,1,0,60\n" +
+			"B1,E1,STAGECOACH,1,1,600\n",
+	})
+
+	pathwayIssues := func(issues []ValidationIssue) []string {
+		codes := []string{CodeInvalidParentStation, CodePathwayBetweenStations, CodeInconsistentPathway,
+			CodeUnreachablePlatform, CodeEntranceWithoutPath}
+		var out []string
+		for _, issue := range issues {
+			if slices.Contains(codes, issue.Code) {
+				out = append(out, issue.Message)
+			}
+		}
+		return out
+	}
+
+	issues, err := Validate(input, nil)
+	require.ErrorIs(t, err, ErrInvalidInput)
+	assert.ElementsMatch(t, []string{
+		"parent_station N1 in stops.txt is a generic node, but the parent of a boarding area must be a stop or platform",
+		"parent_station E1 in stops.txt is an entrance, but the parent of a stop or platform must be a station",
+		"B1 in pathways.txt connects E1 in station BEATTY_AIRPORT_STATION to STAGECOACH in station STAGECOACH_STATION",
+		"X1 in pathways.txt is bidirectional but is an exit gate",
+		"W2 in pathways.txt is bidirectional with traversal_time 20, but W3 between the same stops has 25",
+		"P2 in stops.txt is a platform of station BEATTY_AIRPORT_STATION that no entrance has a pathway to",
+		"E3 in stops.txt is an entrance of station BEATTY_AIRPORT_STATION with no pathway to any platform",
+	}, pathwayIssues(issues))
+
+	t.Run("fix", func(t *testing.T) {
+		outDir := testTempdir(t)
+		issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ForceValid: true})
+		require.NoError(t, err)
+
+		conn, err := sqlite.OpenConn(outDir+"/imported.db", sqlite.SQLITE_OPEN_READONLY)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		// Only the errors are deleted
+		var remaining []string
+		err = sqlitex.Exec(conn, "SELECT pathway_id FROM pathways ORDER BY rowid", func(stmt *sqlite.Stmt) error {
+			remaining = append(remaining, stmt.GetText("pathway_id"))
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"W1", "W2", "W3", "X1", "W4"}, remaining)
+
+		// The platform is taken out of the station, but the boarding area must have a parent so is deleted
+		parents := make(map[string]string)
+		err = sqlitex.Exec(conn, "SELECT stop_id, parent_station FROM stops WHERE stop_id IN ('BA_BAD', 'P3')",
+			func(stmt *sqlite.Stmt) error {
+				parents[stmt.GetText("stop_id")] = stmt.GetText("parent_station")
+				return nil
+			})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"P3": ""}, parents)
+
+		repairs := make(map[string]Repair)
+		for _, issue := range issues {
+			if issue.Code == CodeInvalidParentStation {
+				repairs[issue.Row["stop_id"]] = issue.Repair
+			}
+		}
+		assert.Equal(t, map[string]Repair{"P3": RepairNullify, "BA_BAD": RepairDelete}, repairs)
+	})
+}
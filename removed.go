@@ -0,0 +1,313 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// removedSchema records rows deleted by ForceValid so they can be reviewed and restored
+const removedSchema = `
+CREATE TABLE IF NOT EXISTS __gtfs2sqlite_removed (
+	id INTEGER PRIMARY KEY, tableName TEXT, rowID INTEGER, row TEXT, pass INTEGER, code TEXT, message TEXT
+)`
+
+// toDeleteSchema holds the rowids of rows to delete from a table, and the issue that caused each to be deleted
+const toDeleteSchema = `
+CREATE TEMP TABLE IF NOT EXISTS __gtfs2sqlite_to_delete (id INTEGER PRIMARY KEY, code TEXT, message TEXT)`
+
+// removedOriginalText is the tableName of original text moved from deleted rows, whose rowID is then the id of the
+// row in __gtfs2sqlite_removed. This stops a later row given the same rowid being exported with the deleted row's
+// text, and lets a restored row keep it.
+const removedOriginalText = "__gtfs2sqlite_removed"
+
+// removeRows deletes rows from table, first copying them into __gtfs2sqlite_removed with their values as a JSON
+// object. Returns how many rows were deleted.
+func removeRows(db *sqlite.Conn, table string, removals []removal, pass int) (deleted int, err error) {
+	if err := sqlitex.ExecTransient(db, removedSchema, sqlitexNoop); err != nil {
+		return 0, err
+	}
+	if err := sqlitex.ExecScript(db, originalTextSchema); err != nil {
+		return 0, err
+	}
+	if err := sqlitex.ExecTransient(db, toDeleteSchema, sqlitexNoop); err != nil {
+		return 0, err
+	}
+	defer func() {
+		clearErr := sqlitex.Exec(db, "DELETE FROM temp.__gtfs2sqlite_to_delete", sqlitexNoop)
+		if err == nil {
+			err = clearErr
+		}
+	}()
+
+	// A row removed more than once is recorded with the first issue
+	for _, r := range removals {
+		err := sqlitex.Exec(db, "INSERT OR IGNORE INTO temp.__gtfs2sqlite_to_delete (id, code, message) VALUES (?, ?, ?)",
+			sqlitexNoop, r.rowid, r.issue.Code, r.issue.Message)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	columns, err := tableColumns(db, table)
+	if err != nil {
+		return 0, err
+	}
+	var fields []string
+	for _, column := range columns {
+		fields = append(fields, fmt.Sprintf("'%s', t.%s", column, column))
+	}
+	lastID, err := lastRemovedID(db)
+	if err != nil {
+		return 0, err
+	}
+	query := fmt.Sprintf(`
+INSERT INTO __gtfs2sqlite_removed (tableName, rowID, row, pass, code, message)
+SELECT ?, t.rowid, json_object(%s), ?, d.code, d.message
+FROM %s AS t JOIN temp.__gtfs2sqlite_to_delete AS d ON d.id = t.rowid
+ORDER BY t.rowid`, strings.Join(fields, ", "), table)
+	if err := sqlitex.Exec(db, query, sqlitexNoop, table, pass); err != nil {
+		return 0, err
+	}
+
+	query = `
+UPDATE __gtfs2sqlite_original_text SET tableName = ?, rowID = (
+	SELECT r.id FROM __gtfs2sqlite_removed AS r
+	WHERE r.id > ? AND r.tableName = __gtfs2sqlite_original_text.tableName AND r.rowID = __gtfs2sqlite_original_text.rowID
+)
+WHERE tableName = ? AND rowID IN (SELECT id FROM temp.__gtfs2sqlite_to_delete)`
+	if err := sqlitex.Exec(db, query, sqlitexNoop, removedOriginalText, lastID, table); err != nil {
+		return 0, err
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE rowid IN (SELECT id FROM temp.__gtfs2sqlite_to_delete)", table)
+	if err := sqlitex.Exec(db, query, sqlitexNoop); err != nil {
+		return 0, err
+	}
+	return db.Changes(), nil
+}
+
+// RemovedRow is a row deleted by ForceValid
+type RemovedRow struct {
+	// Table is the name of the file without the .txt extension
+	Table string `json:"table"`
+	RowID int64  `json:"rowid"`
+	// Row holds the non-empty fields of the row
+	Row map[string]string `json:"row,omitempty"`
+	// Pass, Code and Message are of the issue that caused the row to be deleted
+	Pass    int    `json:"pass"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (r RemovedRow) String() string {
+	return fmt.Sprintf("%s [%s]", r.Message, formatRow(r.Row))
+}
+
+// lastRemovedID returns the id of the last row in __gtfs2sqlite_removed, or zero if there are none
+func lastRemovedID(db *sqlite.Conn) (int64, error) {
+	if err := sqlitex.ExecTransient(db, removedSchema, sqlitexNoop); err != nil {
+		return 0, err
+	}
+	var id int64
+	err := sqlitex.Exec(db, "SELECT coalesce(max(id), 0) FROM __gtfs2sqlite_removed", func(stmt *sqlite.Stmt) error {
+		id = stmt.ColumnInt64(0)
+		return nil
+	})
+	return id, err
+}
+
+// readRemoved reads the rows in __gtfs2sqlite_removed matching where
+func readRemoved(db *sqlite.Conn, where string, args ...any) ([]RemovedRow, error) {
+	var removed []RemovedRow
+	var rows []string // as JSON
+	query := fmt.Sprintf("SELECT * FROM __gtfs2sqlite_removed WHERE %s ORDER BY id", where)
+	err := sqlitex.Exec(db, query, func(stmt *sqlite.Stmt) error {
+		removed = append(removed, RemovedRow{
+			Table:   stmt.GetText("tableName"),
+			RowID:   stmt.GetInt64("rowID"),
+			Pass:    int(stmt.GetInt64("pass")),
+			Code:    stmt.GetText("code"),
+			Message: stmt.GetText("message"),
+		})
+		rows = append(rows, stmt.GetText("row"))
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range removed {
+		table := removed[i].Table
+		removed[i].Row = make(map[string]string)
+		err := sqlitex.Exec(db, "SELECT key, value FROM json_each(?)", func(stmt *sqlite.Stmt) error {
+			column := stmt.ColumnText(0)
+			value := formatValue(gtfsSchema[table].Columns[column].kind(), columnValue(stmt, 1))
+			if value != "" {
+				removed[i].Row[column] = value
+			}
+			return nil
+		}, rows[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
+
+type RestoreOpts struct {
+	// Tables restricts which tables rows are restored to. If empty rows are restored to every table.
+	Tables []string
+}
+
+// RestoreRemoved reinserts rows deleted by ForceValid into the database at path, returning how many were restored.
+// Rows keep their original rowid unless it has been reused. Rows whose primary key is now used by another row, such
+// as duplicates, are skipped and returned, and stay in __gtfs2sqlite_removed. Run Validate afterwards to check the
+// restored rows are now valid.
+func RestoreRemoved(path string, opts *RestoreOpts) (int, []RemovedRow, error) {
+	if path == "" {
+		panic("Missing path")
+	}
+
+	if opts == nil {
+		opts = &RestoreOpts{}
+	}
+
+	db, err := sqlite.OpenConn(path, sqlite.SQLITE_OPEN_READWRITE)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	var exists bool
+	err = sqlitex.Exec(db, "SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = '__gtfs2sqlite_removed'",
+		func(stmt *sqlite.Stmt) error {
+			exists = true
+			return nil
+		})
+	if err != nil || !exists {
+		return 0, nil, err
+	}
+
+	restored, skipped, err := restoreRemovedIn(db, opts)
+	if err != nil {
+		return 0, nil, err
+	}
+	slog.Info(fmt.Sprintf("Restored %d row(s)", restored))
+	if len(skipped) > 0 {
+		slog.Warn(fmt.Sprintf("Skipped %d row(s) whose primary key is now used", len(skipped)))
+	}
+	return restored, skipped, nil
+}
+
+func restoreRemovedIn(db *sqlite.Conn, opts *RestoreOpts) (restored int, skipped []RemovedRow, err error) {
+	defer sqlitex.Save(db)(&err)
+
+	if err := sqlitex.ExecScript(db, originalTextSchema); err != nil {
+		return 0, nil, err
+	}
+
+	type removedRow struct {
+		id    int64
+		table string
+		rowid int64
+	}
+	var rows []removedRow
+	err = sqlitex.Exec(db, "SELECT id, tableName, rowID FROM __gtfs2sqlite_removed ORDER BY id",
+		func(stmt *sqlite.Stmt) error {
+			row := removedRow{id: stmt.GetInt64("id"), table: stmt.GetText("tableName"), rowid: stmt.GetInt64("rowID")}
+			if len(opts.Tables) == 0 || slices.Contains(opts.Tables, row.table) {
+				rows = append(rows, row)
+			}
+			return nil
+		})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var skippedIDs []string
+	for _, row := range rows {
+		conflicts, err := removedKeyConflicts(db, row.table, row.id)
+		if err != nil {
+			return 0, nil, err
+		}
+		if conflicts {
+			skippedIDs = append(skippedIDs, strconv.FormatInt(row.id, 10))
+			continue
+		}
+
+		columns, err := tableColumns(db, row.table)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		var values []string
+		for _, column := range columns {
+			values = append(values, fmt.Sprintf("json_extract(row, '$.%s')", column))
+		}
+		// A reused rowid is left to SQLite to pick a new one
+		query := fmt.Sprintf(`
+INSERT INTO %s (rowid, %s)
+SELECT CASE WHEN EXISTS (SELECT 1 FROM %s WHERE rowid = ?) THEN NULL ELSE ? END, %s
+FROM __gtfs2sqlite_removed WHERE id = ?`,
+			row.table, strings.Join(columns, ", "), row.table, strings.Join(values, ", "))
+		err = sqlitex.Exec(db, query, sqlitexNoop, row.rowid, row.rowid, row.id)
+		if err != nil {
+			return 0, nil, fmt.Errorf("restore row %d of %s: %w", row.rowid, row.table, err)
+		}
+		err = sqlitex.Exec(db, "UPDATE __gtfs2sqlite_original_text SET tableName = ?, rowID = ? WHERE tableName = ? AND rowID = ?",
+			sqlitexNoop, row.table, db.LastInsertRowID(), removedOriginalText, row.id)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		err = sqlitex.Exec(db, "DELETE FROM __gtfs2sqlite_removed WHERE id = ?", sqlitexNoop, row.id)
+		if err != nil {
+			return 0, nil, err
+		}
+		restored++
+	}
+
+	if len(skippedIDs) > 0 {
+		skipped, err = readRemoved(db, fmt.Sprintf("id IN (%s)", strings.Join(skippedIDs, ", ")))
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	return restored, skipped, nil
+}
+
+// removedKeyConflicts is whether the primary key of the removed row with id is used by a row in table
+func removedKeyConflicts(db *sqlite.Conn, table string, id int64) (bool, error) {
+	key := gtfsSchema[table].PrimaryKey
+	if len(key) == 0 {
+		return false, nil
+	}
+	var conditions []string
+	for _, column := range key {
+		conditions = append(conditions, fmt.Sprintf("t.%s IS json_extract(r.row, '$.%s')", column, column))
+	}
+	query := fmt.Sprintf(
+		"SELECT EXISTS (SELECT 1 FROM %s AS t WHERE %s) FROM __gtfs2sqlite_removed AS r WHERE r.id = ?",
+		table, strings.Join(conditions, " AND "))
+
+	var conflicts bool
+	err := sqlitex.Exec(db, query, func(stmt *sqlite.Stmt) error {
+		conflicts = stmt.ColumnInt(0) == 1
+		return nil
+	}, id)
+	return conflicts, err
+}
+
+func tableColumns(db *sqlite.Conn, table string) ([]string, error) {
+	var columns []string
+	err := sqlitex.Exec(db, "SELECT name FROM pragma_table_info(?)", func(stmt *sqlite.Stmt) error {
+		columns = append(columns, stmt.GetText("name"))
+		return nil
+	}, table)
+	return columns, err
+}
@@ -0,0 +1,186 @@
+package gtfs2sqlite
+
+import (
+	"archive/zip"
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"encoding/csv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestForceValidRecordsRemoved(t *testing.T) {
+	outDir := testTempdir(t)
+	_, err := Import("./sample_data/invalid-foreign-key.zip", outDir+"/feed.db", &ImportOpts{
+		ForceValid: true, Rules: deleteInvalidForeignIDs, ReferenceDate: sampleDate,
+	})
+	require.NoError(t, err)
+
+	conn, err := sqlite.OpenConn(outDir+"/feed.db", sqlite.SQLITE_OPEN_READONLY)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	type removed struct {
+		row     string
+		pass    int64
+		code    string
+		message string
+	}
+	byTable := make(map[string][]removed)
+	err = sqlitex.Exec(conn, "SELECT * FROM __gtfs2sqlite_removed ORDER BY rowID", func(stmt *sqlite.Stmt) error {
+		table := stmt.GetText("tableName")
+		byTable[table] = append(byTable[table], removed{
+			row:     stmt.GetText("row"),
+			pass:    stmt.GetInt64("pass"),
+			code:    stmt.GetText("code"),
+			message: stmt.GetText("message"),
+		})
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, byTable["routes"], 1)
+	assert.Len(t, byTable["trips"], 2)
+	assert.Len(t, byTable["stop_times"], 4)
+	assert.Len(t, byTable["fare_rules"], 1)
+
+	route := byTable["routes"][0]
+	assert.JSONEq(t, `{
+		"route_id": "AB", "agency_id": "nonexistent_agency", "route_short_name": "10",
+		"route_long_name": "Airport - Bullfrog", "route_type": 3,
+		"route_desc": null, "route_url": null, "route_color": null, "route_text_color": null,
+		"route_sort_order": null, "continuous_pickup": null, "continuous_drop_off": null, "network_id": null
+	}`, route.row)
+	assert.Equal(t, int64(0), route.pass)
+	assert.Equal(t, CodeInvalidForeignID, route.code)
+	assert.Equal(t, "nonexistent_agency in routes.txt is not a valid agency_id", route.message)
+
+	assert.Equal(t, int64(1), byTable["trips"][0].pass)
+	assert.Equal(t, int64(2), byTable["stop_times"][0].pass)
+	assert.Equal(t, "AB1 in stop_times.txt is not a valid trip_id", byTable["stop_times"][0].message)
+}
+
+func TestRestoreRemoved(t *testing.T) {
+	outDir := testTempdir(t)
+	_, err := Import("./sample_data/invalid-foreign-key.zip", outDir+"/feed.db", &ImportOpts{
+		ForceValid: true, Rules: deleteInvalidForeignIDs, ReferenceDate: sampleDate,
+	})
+	require.NoError(t, err)
+
+	restored, skipped, err := RestoreRemoved(outDir+"/feed.db", &RestoreOpts{Tables: []string{"routes"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored)
+	assert.Empty(t, skipped)
+
+	restored, _, err = RestoreRemoved(outDir+"/feed.db", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 7, restored)
+
+	restored, _, err = RestoreRemoved(outDir+"/feed.db", nil)
+	require.NoError(t, err)
+	assert.Zero(t, restored)
+
+	// Fix the referenced entity by hand
+	conn, err := sqlite.OpenConn(outDir+"/feed.db", 0)
+	require.NoError(t, err)
+	err = sqlitex.Exec(conn, "UPDATE routes SET agency_id = 'DTA' WHERE route_id = 'AB'", sqlitexNoop)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	issues, err := Validate(outDir+"/feed.db", &ValidateOpts{ReferenceDate: sampleDate})
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	err = Export(outDir+"/feed.db", outDir+"/exported.zip", nil)
+	require.NoError(t, err)
+	assertGTFSEqual(t, "./sample_data/sample-feed.zip", outDir+"/exported.zip")
+}
+
+func TestRestoreRemovedDuplicate(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"levels.txt": "level_id,level_index,level_name\n" +
+			"L1,0,Ground\n" +
+			"L1,1,Mezzanine\n",
+		"agency.txt": "agency_id,agency_name,agency_url,agency_timezone\n" +
+			"DTA,Demo Transit Authority,http://google.com,America/Los_Angeles\n" +
+			"OTHER,Other Transit Authority,http://google.com,America/Los_Angeles\n",
+	})
+	outDir := testTempdir(t)
+	_, err := Import(input, outDir+"/feed.db", &ImportOpts{ForceValid: true, Rules: &Rules{
+		Disabled:   []string{CodeConditionallyRequired + ":fare_attributes.agency_id"},
+		Severities: map[string]Severity{CodeUnusedEntity + ":agency.agency_id": SeverityError},
+	}, ReferenceDate: sampleDate})
+	require.NoError(t, err)
+
+	// The duplicate is skipped without stopping the agency being restored
+	restored, skipped, err := RestoreRemoved(outDir+"/feed.db", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored)
+	require.Len(t, skipped, 1)
+	assert.Equal(t, "levels", skipped[0].Table)
+	assert.Equal(t, map[string]string{"level_id": "L1", "level_index": "1", "level_name": "Mezzanine"}, skipped[0].Row)
+
+	conn, err := sqlite.OpenConn(outDir+"/feed.db", sqlite.SQLITE_OPEN_READONLY)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	var remaining []string
+	err = sqlitex.Exec(conn, "SELECT tableName FROM __gtfs2sqlite_removed", func(stmt *sqlite.Stmt) error {
+		remaining = append(remaining, stmt.GetText("tableName"))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"levels"}, remaining)
+}
+
+func TestRemovedOriginalText(t *testing.T) {
+	outDir := testTempdir(t)
+	_, err := Import("./sample_data/invalid-foreign-key.zip", outDir+"/feed.db", &ImportOpts{
+		ForceValid: true, Rules: deleteInvalidForeignIDs, ReferenceDate: sampleDate,
+	})
+	require.NoError(t, err)
+
+	// Reuse the rowid of a deleted stop time, which was imported from 8:00:00
+	conn, err := sqlite.OpenConn(outDir+"/feed.db", 0)
+	require.NoError(t, err)
+	var rowid int64
+	err = sqlitex.Exec(conn, `SELECT rowID FROM __gtfs2sqlite_removed
+		WHERE tableName = 'stop_times' AND json_extract(row, '$.trip_id') = 'AB1' AND json_extract(row, '$.stop_sequence') = 1`,
+		func(stmt *sqlite.Stmt) error {
+			rowid = stmt.GetInt64("rowID")
+			return nil
+		})
+	require.NoError(t, err)
+	require.NotZero(t, rowid)
+	err = sqlitex.Exec(conn, `INSERT INTO stop_times (rowid, trip_id, arrival_time, departure_time, stop_id, stop_sequence)
+		VALUES (?, 'STBA', 28800, 28800, 'BEATTY_AIRPORT', 3)`, sqlitexNoop, rowid)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	// The restored stop time is given a new rowid but keeps its original text
+	restored, _, err := RestoreRemoved(outDir+"/feed.db", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 8, restored)
+
+	err = Export(outDir+"/feed.db", outDir+"/exported.zip", nil)
+	require.NoError(t, err)
+	exported, err := zip.OpenReader(outDir + "/exported.zip")
+	require.NoError(t, err)
+	defer func() { _ = exported.Close() }()
+	f, err := exported.Open("stop_times.txt")
+	require.NoError(t, err)
+	records, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+
+	arrivals := make(map[string]string)
+	header := records[0]
+	for _, record := range records[1:] {
+		row := make(map[string]string)
+		for i, column := range header {
+			row[column] = record[i]
+		}
+		arrivals[row["trip_id"]+" "+row["stop_sequence"]] = row["arrival_time"]
+	}
+	assert.Equal(t, "8:00:00", arrivals["AB1 1"])
+	assert.Equal(t, "08:00:00", arrivals["STBA 3"])
+}
@@ -0,0 +1,209 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Repair is how ForceValid fixes an issue
+type Repair string
+
+const (
+	// RepairDelete deletes the row with the issue. It is used when no other repair applies.
+	RepairDelete Repair = "delete"
+	// RepairNullify clears the value of an optional column
+	RepairNullify Repair = "nullify"
+	// RepairDefault replaces an invalid enum value with the default for the column
+	RepairDefault Repair = "default"
+	// RepairPlaceholder creates the entity a foreign ID references. Any required columns of the placeholder are set
+	// to the default for enums and to the referenced ID for text. Placeholders are validated like any other row.
+	// Only entities that can be valid without more information can have placeholders, which are areas,
+	// attributions, location_groups and networks. This is deliberately narrow: a placeholder stop or route would
+	// need details nobody has, so a reference to one that doesn't exist is more likely a typo, which
+	// RepairRewrite fixes.
+	RepairPlaceholder Repair = "placeholder"
+	// RepairRewrite replaces an invalid foreign ID with the existing ID suggested for it, if only one was
+	RepairRewrite Repair = "rewrite"
+)
+
+var repairs = []Repair{RepairDelete, RepairNullify, RepairDefault, RepairPlaceholder, RepairRewrite}
+
+// defaultRepairs are tried for issues Rules don't list repairs for, keyed like Rules.Repairs
+var defaultRepairs = map[string][]Repair{
+	// A stop with the wrong parent is still usable on its own
+	CodeInvalidParentStation + ":stops.parent_station": {RepairNullify},
+	// Without its own timezone a stop inherits the timezone of its parent station
+	CodeInconsistentTimezone + ":stops.stop_timezone": {RepairNullify},
+	// A trip taken out of its block is still run for riders, so only the vehicle schedule is lost
+	CodeOverlappingBlockTrips + ":trips.block_id": {RepairNullify},
+}
+
+// unrepairable lists issues ForceValid leaves for the user to fix rather than deleting the row, keyed like
+// Rules.Repairs. Repairs listed in Rules are still tried.
+var unrepairable = []string{
+	// Deleting an agency would delete everything it runs
+	CodeInconsistentTimezone + ":agency.agency_timezone",
+}
+
+// wildcardForeignIDTables are tables where an empty foreign ID matches every entity, so clearing an invalid one
+// would widen the rule it is part of rather than drop it
+var wildcardForeignIDTables = []string{"attributions", "fare_rules", "fare_leg_rules", "fare_transfer_rules", "transfers"}
+
+type update struct {
+	table  string
+	column string
+	rowid  int64
+	value  any // nil to nullify
+}
+
+// placeholder is an entity to create in table with the ID id
+type placeholder struct {
+	table string
+	id    string
+}
+
+func placeholderFor(issue ValidationIssue) placeholder {
+	ref := gtfsSchema[issue.Table].Columns[issue.Column].ForeignID
+	if ref == nil {
+		return placeholder{}
+	}
+	return placeholder{table: ref.Table, id: issue.Value}
+}
+
+// repairFor picks the first applicable repair configured for an issue, or "" if the issue isn't to be fixed or
+// can't be
+func (v *validator) repairFor(issue ValidationIssue) Repair {
+	if !v.fixes(issue.Code, issue.Table, issue.Column) {
+		return ""
+	}
+
+	scopedKey := fmt.Sprintf("%s:%s.%s", issue.Code, issue.Table, issue.Column)
+	preferred := v.opts.rules.repairs(issue.Code, issue.Table, issue.Column)
+	if preferred == nil {
+		preferred = defaultRepairs[scopedKey]
+	}
+	if preferred == nil && v.opts.coerceEnums && issue.Code == CodeInvalidEnum {
+		preferred = []Repair{RepairDefault}
+	}
+	// A broken optional reference, such as to a shape or level, is cleared rather than deleting the row
+	if preferred == nil && issue.Code == CodeInvalidForeignID && !slices.Contains(wildcardForeignIDTables, issue.Table) {
+		preferred = []Repair{RepairNullify}
+	}
+
+	for _, repair := range preferred {
+		if repair == RepairPlaceholder && v.placeholders[placeholderFor(issue)] {
+			// The placeholder was invalid and has been repaired itself
+			continue
+		}
+		if canRepair(repair, issue) {
+			return repair
+		}
+	}
+	if slices.Contains(unrepairable, scopedKey) {
+		return ""
+	}
+	return RepairDelete
+}
+
+func canRepair(repair Repair, issue ValidationIssue) bool {
+	if issue.RowID == 0 {
+		return false
+	}
+	schema, ok := gtfsSchema[issue.Table].Columns[issue.Column]
+	if !ok {
+		return repair == RepairDelete
+	}
+
+	switch repair {
+	case RepairDelete:
+		return true
+	case RepairNullify:
+		nullifiable := []string{CodeInvalidForeignID, CodeInvalidValue, CodeInvalidEnum, CodeConditionallyForbidden,
+			CodeOverlappingBlockTrips, CodeInvalidParentStation, CodeInconsistentTimezone}
+		return schema.PresenceDescription != "Required" && slices.Contains(nullifiable, issue.Code)
+	case RepairDefault:
+		return issue.Code == CodeInvalidEnum && schema.Enum != nil && schema.Enum.Default != nil
+	case RepairPlaceholder:
+		return issue.Code == CodeInvalidForeignID && canReferencePlaceholder(schema)
+	case RepairRewrite:
+		return issue.Code == CodeInvalidForeignID && len(issue.Suggestions) == 1
+	default:
+		return false
+	}
+}
+
+// placeholderTables lists the tables RepairPlaceholder can create rows in
+func placeholderTables() []string {
+	var tables []string
+	for table := range gtfsSchema {
+		if canHavePlaceholder(table) {
+			tables = append(tables, table)
+		}
+	}
+	slices.Sort(tables)
+	return tables
+}
+
+// canHavePlaceholder is whether a placeholder in table would be valid. Only entities can have placeholders, not
+// things like zone IDs, and only if every required column can be filled in.
+func canHavePlaceholder(table string) bool {
+	if len(gtfsSchema[table].PrimaryKey) != 1 {
+		return false
+	}
+	_, _, ok := placeholderColumns(table, "")
+	return ok
+}
+
+// canReferencePlaceholder is whether an invalid foreign ID in a column can be repaired with a placeholder
+func canReferencePlaceholder(schema columnSchema) bool {
+	ref := schema.ForeignID
+	return ref != nil && ref.Table != "" && canHavePlaceholder(ref.Table) &&
+		gtfsSchema[ref.Table].PrimaryKey[0] == ref.Column
+}
+
+// placeholderColumns returns the columns and values of a placeholder. ok is false if a required column can't be
+// filled in. Columns a presence rule requires are treated as required, so that placeholders like stops without a
+// location, which would be invalid and deleted, aren't created.
+func placeholderColumns(table, id string) (columns []string, values []any, ok bool) {
+	key := gtfsSchema[table].PrimaryKey[0]
+	columns = []string{key}
+	values = []any{id}
+	for column, schema := range gtfsSchema[table].Columns {
+		if column == key || (schema.PresenceDescription != "Required" && !requiredByRule(table, column)) {
+			continue
+		}
+		switch {
+		case schema.Enum != nil && schema.Enum.Default != nil:
+			values = append(values, *schema.Enum.Default)
+		case schema.TypeDescription == "Text" || schema.TypeDescription == "ID" || schema.TypeDescription == "Unique ID":
+			values = append(values, id)
+		default:
+			return nil, nil, false
+		}
+		columns = append(columns, column)
+	}
+	return columns, values, true
+}
+
+// requiredByRule is whether a presence rule can require column
+func requiredByRule(table, column string) bool {
+	return slices.ContainsFunc(presenceRules, func(rule presenceRule) bool {
+		return rule.Table == table && rule.Column == column && !rule.Forbidden
+	})
+}
+
+// createPlaceholder inserts a placeholder unless one has already been created
+func createPlaceholder(db *sqlite.Conn, p placeholder) (created bool, err error) {
+	columns, values, _ := placeholderColumns(p.table, p.id)
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s WHERE NOT EXISTS (SELECT 1 FROM %s WHERE %s = ?)",
+		p.table, strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", "),
+		p.table, columns[0])
+	if err := sqlitex.Exec(db, query, sqlitexNoop, append(values, p.id)...); err != nil {
+		return false, err
+	}
+	return db.Changes() > 0, nil
+}
@@ -0,0 +1,163 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRepairs(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"trips.txt": "route_id,service_id,trip_id,trip_headsign,direction_id,block_id,shape_id\n" +
+			"AB,FULLW,AB1,to Bullfrog,0,1,nonexistent_shape\n" +
+			"AB,FULLW,AB2,to Airport,1,2,\n" +
+			"STBA,FULLW,STBA,Shuttle,,,\n" +
+			"CITY,FULLW,CITY1,,0,,\n" +
+			"CITY,FULLW,CITY2,,1,,\n" +
+			"BFC,FULLW,BFC1,to Furnace Creek Resort,0,1,\n" +
+			"BFC,FULLW,BFC2,to Bullfrog,1,2,\n" +
+			"nonexistent_route,WE,AAMV1,to Amargosa Valley,0,,\n" +
+			"AAMV,WE,AAMV2,to Airport,1,,\n" +
+			"AAMV,WE,AAMV3,to Amargosa Valley,0,,\n" +
+			"AAMV,WE,AAMV4,to Airport,1,,\n",
+		"frequencies.txt": "trip_id,start_time,end_time,headway_secs,exact_times\n" +
+			"STBA,6:00:00,22:00:00,1800,2\n",
+		"route_networks.txt": "network_id,route_id\n" +
+			"nonexistent_network,AB\n",
+		"stop_areas.txt": "area_id,stop_id\n" +
+			"nonexistent_area,nonexistent_stop\n",
+	})
+	rules := &Rules{Repairs: map[string][]Repair{
+		CodeInvalidForeignID: {RepairPlaceholder, RepairNullify},
+		CodeInvalidEnum:      {RepairDefault},
+	}}
+
+	outDir := testTempdir(t)
+	issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ForceValid: true, Rules: rules, ReferenceDate: sampleDate})
+	require.NoError(t, err)
+
+	repairs := make(map[string]Repair)
+	for _, issue := range issues {
+		repairs[issue.Table+"."+issue.Column+" "+issue.Value] = issue.Repair
+		assert.Equal(t, issue.Repair == RepairDelete, issue.Deleted)
+	}
+	assert.Equal(t, map[string]Repair{
+		// Optional
+		"trips.shape_id nonexistent_shape": RepairNullify,
+		// Required, and routes need an agency_url so can't have a placeholder
+		"trips.route_id nonexistent_route":              RepairDelete,
+		"stop_times.trip_id AAMV1":                      RepairDelete,
+		"frequencies.exact_times 2":                     RepairDefault,
+		"route_networks.network_id nonexistent_network": RepairPlaceholder,
+		"stop_areas.area_id nonexistent_area":           RepairPlaceholder,
+		// Stops need a location so can't have a placeholder either
+		"stop_areas.stop_id nonexistent_stop": RepairDelete,
+	}, repairs)
+
+	conn, err := sqlite.OpenConn(outDir+"/imported.db", sqlite.SQLITE_OPEN_READONLY)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	query := func(query string) []string {
+		var out []string
+		err := sqlitex.Exec(conn, query, func(stmt *sqlite.Stmt) error {
+			out = append(out, stmt.ColumnText(0))
+			return nil
+		})
+		require.NoError(t, err)
+		return out
+	}
+	assert.Equal(t, []string{""}, query("SELECT shape_id FROM trips WHERE trip_id = 'AB1'"))
+	assert.Empty(t, query("SELECT trip_id FROM trips WHERE trip_id = 'AAMV1'"))
+	assert.Equal(t, []string{"0"}, query("SELECT exact_times FROM frequencies"))
+	assert.Equal(t, []string{"nonexistent_network"}, query("SELECT network_id FROM networks"))
+	assert.Equal(t, []string{"nonexistent_area"}, query("SELECT area_id FROM areas"))
+	assert.Empty(t, query("SELECT stop_id FROM stops WHERE stop_id = 'nonexistent_stop'"))
+	assert.Empty(t, query("SELECT area_id FROM stop_areas"))
+}
+
+func TestRepairSuperseded(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"routes.txt": "route_id,agency_id,route_short_name,route_long_name,route_type\n" +
+			"AB,DTA,10,Airport - Bullfrog,99\n" +
+			"BFC,DTA,20,Bullfrog - Furnace Creek Resort,3\n" +
+			"STBA,DTA,30,Stagecoach - Airport Shuttle,3\n" +
+			"CITY,DTA,40,City,3\n" +
+			"AAMV,DTA,50,Airport - Amargosa Valley,3\n",
+		"trips.txt": "route_id,service_id,trip_id\n" +
+			"ab,FULLW,AB1\n" +
+			"AB,FULLW,AB2\n" +
+			"STBA,FULLW,STBA\n" +
+			"CITY,FULLW,CITY1\n" +
+			"CITY,FULLW,CITY2\n" +
+			"BFC,FULLW,BFC1\n" +
+			"BFC,FULLW,BFC2\n" +
+			"AAMV,WE,AAMV1\n" +
+			"AAMV,WE,AAMV2\n" +
+			"AAMV,WE,AAMV3\n" +
+			"AAMV,WE,AAMV4\n",
+	})
+	rules := &Rules{Repairs: map[string][]Repair{CodeInvalidForeignID: {RepairRewrite}}}
+
+	outDir := testTempdir(t)
+	issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ForceValid: true, Rules: rules, ReferenceDate: sampleDate})
+	require.NoError(t, err)
+
+	// The trip is rewritten to run on AB, which is deleted, so the trip is deleted on the next pass
+	var found bool
+	for _, issue := range issues {
+		if issue.Code == CodeInvalidForeignID && issue.Value == "ab" {
+			found = true
+			assert.Equal(t, RepairDelete, issue.Repair)
+			assert.True(t, issue.Deleted)
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestDefaultRepairs(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"trips.txt": "route_id,service_id,trip_id,shape_id\n" +
+			"AB,FULLW,AB1,nonexistent_shape\n" +
+			"AB,FULLW,AB2,\n" +
+			"STBA,FULLW,STBA,\n" +
+			"CITY,FULLW,CITY1,\n" +
+			"CITY,FULLW,CITY2,\n" +
+			"BFC,FULLW,BFC1,\n" +
+			"BFC,FULLW,BFC2,\n" +
+			"AAMV,WE,AAMV1,\n" +
+			"AAMV,WE,AAMV2,\n" +
+			"AAMV,WE,AAMV3,\n" +
+			"AAMV,WE,AAMV4,\n",
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon,level_id\n" +
+			"FUR_CREEK_RES,Furnace Creek Resort (Demo),36.425288,-117.133162,nonexistent_level\n" +
+			"BEATTY_AIRPORT,Nye County Airport (Demo),36.868446,-116.784582,\n" +
+			"BULLFROG,Bullfrog (Demo),36.88108,-116.81797,\n" +
+			"STAGECOACH,Stagecoach Hotel & Casino (Demo),36.915682,-116.751677,\n" +
+			"NADAV,North Ave / D Ave N (Demo),36.914893,-116.76821,\n" +
+			"NANAA,North Ave / N A Ave (Demo),36.914944,-116.761472,\n" +
+			"DADAN,Doing Ave / D Ave N (Demo),36.909489,-116.768242,\n" +
+			"EMSI,E Main St / S Irving St (Demo),36.905697,-116.76218,\n" +
+			"AMV,Amargosa Valley (Demo),36.641496,-116.40094,\n",
+		// An empty route_id would make the rule apply to every route
+		"fare_rules.txt": "fare_id,route_id\n" +
+			"p,nonexistent_route\n" +
+			"a,AAMV\n",
+	})
+
+	outDir := testTempdir(t)
+	issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ForceValid: true, ReferenceDate: sampleDate})
+	require.NoError(t, err)
+
+	repairs := make(map[string]Repair)
+	for _, issue := range issues {
+		repairs[issue.Table+"."+issue.Column+" "+issue.Value] = issue.Repair
+	}
+	assert.Equal(t, map[string]Repair{
+		"trips.shape_id nonexistent_shape":      RepairNullify,
+		"stops.level_id nonexistent_level":      RepairNullify,
+		"fare_rules.route_id nonexistent_route": RepairDelete,
+	}, repairs)
+}
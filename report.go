@@ -0,0 +1,99 @@
+package gtfs2sqlite
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"io"
+	"slices"
+)
+
+// reportExampleCount is the maximum number of example rows shown for each group in an HTML report
+const reportExampleCount = 5
+
+type jsonReport struct {
+	Issues []ValidationIssue `json:"issues"`
+}
+
+// WriteJSONReport writes the issues as JSON. Issues are sorted so that reports from different runs on the same
+// input can be diffed.
+func WriteJSONReport(w io.Writer, issues []ValidationIssue) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jsonReport{Issues: sortedIssues(issues)})
+}
+
+//go:embed report.html.tmpl
+var htmlReportSource string
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(htmlReportSource))
+
+type htmlReport struct {
+	Total   int
+	Deleted int
+	Groups  []htmlReportGroup
+}
+
+type htmlReportGroup struct {
+	Code     string
+	Severity Severity
+	Table    string
+	Count    int
+	Deleted  int
+	Examples []ValidationIssue
+	// More is the number of issues in the group not included in the examples
+	More int
+}
+
+// WriteHTMLReport writes a summary of the issues grouped by code, table and severity, with some example rows from
+// each group. Rules can give a code different severities in different columns of a table, so errors are grouped
+// separately from warnings.
+func WriteHTMLReport(w io.Writer, issues []ValidationIssue) error {
+	report := htmlReport{Total: len(issues)}
+	sorted := sortedIssues(issues)
+	slices.SortStableFunc(sorted, func(a, b ValidationIssue) int {
+		return cmp.Or(cmp.Compare(a.Code, b.Code), cmp.Compare(a.Table, b.Table), cmp.Compare(a.Severity, b.Severity))
+	})
+	for _, issue := range sorted {
+		n := len(report.Groups)
+		if n == 0 || report.Groups[n-1].Code != issue.Code || report.Groups[n-1].Table != issue.Table ||
+			report.Groups[n-1].Severity != issue.Severity {
+			report.Groups = append(report.Groups, htmlReportGroup{
+				Code:     issue.Code,
+				Severity: issue.Severity,
+				Table:    issue.Table,
+			})
+			n++
+		}
+		group := &report.Groups[n-1]
+
+		group.Count++
+		if issue.Deleted {
+			group.Deleted++
+			report.Deleted++
+		}
+		if len(group.Examples) < reportExampleCount {
+			group.Examples = append(group.Examples, issue)
+		} else {
+			group.More++
+		}
+	}
+	return htmlReportTemplate.Execute(w, report)
+}
+
+func sortedIssues(issues []ValidationIssue) []ValidationIssue {
+	sorted := slices.Clone(issues)
+	if sorted == nil {
+		sorted = []ValidationIssue{}
+	}
+	slices.SortStableFunc(sorted, func(a, b ValidationIssue) int {
+		return cmp.Or(
+			cmp.Compare(a.Code, b.Code),
+			cmp.Compare(a.Table, b.Table),
+			cmp.Compare(a.RowID, b.RowID),
+			cmp.Compare(a.Column, b.Column),
+		)
+	})
+	return sorted
+}
@@ -0,0 +1,126 @@
+package gtfs2sqlite
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+var reportTestIssues = []ValidationIssue{
+	{
+		Code:     CodeInvalidForeignID,
+		Severity: SeverityError,
+		Message:  "nonexistent_agency in routes.txt is not a valid agency_id",
+		Table:    "routes",
+		Column:   "agency_id",
+		RowID:    2,
+		Value:    "nonexistent_agency",
+		Row:      map[string]string{"route_id": "AB", "agency_id": "nonexistent_agency"},
+		Deleted:  true,
+	},
+	{
+		Code:     CodeMissingRequiredColumn,
+		Severity: SeverityError,
+		Message:  "levels.txt is missing required column level_index",
+		Table:    "levels",
+		Column:   "level_index",
+	},
+	{
+		Code:     CodeInvalidForeignID,
+		Severity: SeverityError,
+		Message:  "<script> in routes.txt is not a valid agency_id",
+		Table:    "routes",
+		Column:   "agency_id",
+		RowID:    1,
+		Value:    "<script>",
+		Row:      map[string]string{"route_id": "BFC", "agency_id": "<script>"},
+	},
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSONReport(&buf, reportTestIssues))
+
+	var report struct {
+		Issues []ValidationIssue `json:"issues"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+	require.Len(t, report.Issues, 3)
+
+	// Sorted by code, table and rowid
+	assert.Equal(t, reportTestIssues[2], report.Issues[0])
+	assert.Equal(t, reportTestIssues[0], report.Issues[1])
+	assert.Equal(t, reportTestIssues[1], report.Issues[2])
+}
+
+func TestWriteJSONReportEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSONReport(&buf, nil))
+	assert.JSONEq(t, `{"issues": []}`, buf.String())
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteHTMLReport(&buf, reportTestIssues))
+	out := buf.String()
+
+	assert.Contains(t, out, "3 issue(s) found, 1 fixed by deleting rows")
+	assert.Contains(t, out, "<code>invalid_foreign_id</code> in routes.txt")
+	assert.Contains(t, out, "<code>missing_required_column</code> in levels.txt")
+	assert.Contains(t, out, "&lt;script&gt; in routes.txt is not a valid agency_id")
+	assert.NotContains(t, out, "<script>")
+}
+
+func TestWriteHTMLReportScopedSeverity(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"routes.txt": "route_id,agency_id,route_short_name,route_long_name,route_type,continuous_pickup\n" +
+			"AB,DTA,10,Airport - Bullfrog,3,9\n" +
+			"BFC,DTA,20,Bullfrog - Furnace Creek Resort,99,\n" +
+			"STBA,DTA,30,Stagecoach - Airport Shuttle,3,9\n" +
+			"CITY,DTA,40,City,3,\n" +
+			"AAMV,DTA,50,Airport - Amargosa Valley,3,\n",
+	})
+	outDir := testTempdir(t)
+	issues, err := Import(input, outDir+"/imported.db", &ImportOpts{
+		IgnoreInvalid: true,
+		Rules: &Rules{Severities: map[string]Severity{
+			CodeInvalidEnum + ":routes.continuous_pickup": SeverityWarning,
+		}},
+		ReferenceDate: sampleDate,
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteHTMLReport(&buf, issues))
+	out := buf.String()
+	// The error isn't listed as a warning, or the warnings as errors
+	assert.Equal(t, 1, strings.Count(out, "<code>invalid_enum</code> in routes.txt (error)"))
+	assert.Equal(t, 1, strings.Count(out, "<code>invalid_enum</code> in routes.txt (warning)"))
+	assert.Regexp(t, `(?s)\(error\)</h2>.*99 in routes.txt.*\(warning\)</h2>.*9 in routes.txt.*9 in routes.txt`, out)
+}
+
+func TestReportForceValidCascade(t *testing.T) {
+	outDir := testTempdir(t)
+	issues, err := Import("./sample_data/invalid-foreign-key.zip", outDir+"/feed.db", &ImportOpts{
+		ForceValid: true, Rules: deleteInvalidForeignIDs, ReferenceDate: sampleDate,
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSONReport(&buf, issues))
+	var report struct {
+		Issues []ValidationIssue `json:"issues"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+
+	// Deleting the route cascades to its trips, their stop times and its fare rules
+	deleted := make(map[string]int)
+	for _, issue := range report.Issues {
+		assert.True(t, issue.Deleted)
+		deleted[issue.Table]++
+	}
+	assert.Equal(t, map[string]int{"routes": 1, "trips": 2, "stop_times": 4, "fare_rules": 1}, deleted)
+}
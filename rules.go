@@ -0,0 +1,149 @@
+package gtfs2sqlite
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Rules configures which checks are run and the severity of the issues they find. Only issues with SeverityError
+// make validation fail or are fixed by ForceValid.
+//
+// Keys are either a code, such as "invalid_enum", a code scoped to a table, such as
+// "duplicate_primary_key:stop_times", or a code scoped to a column, such as "invalid_enum:routes.route_type". The
+// most specific key takes precedence. Issues not about a single column, like duplicate primary keys of more than
+// one column, can only be scoped to their table.
+type Rules struct {
+	// Disabled lists checks that aren't run
+	Disabled []string `json:"disabled,omitempty"`
+	// Severities overrides the default severity of issues
+	Severities map[string]Severity `json:"severities,omitempty"`
+	// Repairs lists the repairs ForceValid should try in order. RepairDelete is used if none apply, or if no
+	// repairs are listed.
+	Repairs map[string][]Repair `json:"repairs,omitempty"`
+}
+
+// ParseRules parses Rules from JSON, checking the codes and severities are known
+func ParseRules(data []byte) (*Rules, error) {
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+
+	for _, key := range rules.Disabled {
+		if err := checkRuleKey(key); err != nil {
+			return nil, err
+		}
+	}
+	for key, severity := range rules.Severities {
+		if err := checkRuleKey(key); err != nil {
+			return nil, err
+		}
+		if severity != SeverityError && severity != SeverityWarning {
+			return nil, fmt.Errorf("invalid severity %q for %s", severity, key)
+		}
+	}
+	for key, keyRepairs := range rules.Repairs {
+		if err := checkRuleKey(key); err != nil {
+			return nil, err
+		}
+		for _, repair := range keyRepairs {
+			if !slices.Contains(repairs, repair) {
+				return nil, fmt.Errorf("invalid repair %q for %s", repair, key)
+			}
+			if repair == RepairPlaceholder {
+				if err := checkPlaceholderKey(key); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return &rules, nil
+}
+
+func checkRuleKey(key string) error {
+	code, scope, scoped := strings.Cut(key, ":")
+	if _, ok := defaultSeverities[code]; !ok {
+		return fmt.Errorf("unknown code %q", code)
+	}
+	if scoped {
+		table, column, hasColumn := strings.Cut(scope, ".")
+		if _, ok := gtfsSchema[table]; !ok {
+			return fmt.Errorf("invalid rule %q (unknown table %s)", key, table)
+		}
+		if _, ok := gtfsSchema[table].Columns[column]; hasColumn && !ok {
+			return fmt.Errorf("invalid rule %q (unknown column %s)", key, column)
+		}
+	}
+	return nil
+}
+
+// checkPlaceholderKey checks placeholders can be created for the issues key applies to
+func checkPlaceholderKey(key string) error {
+	code, scope, scoped := strings.Cut(key, ":")
+	if code != CodeInvalidForeignID {
+		return fmt.Errorf("invalid repair %q for %s (placeholders only repair %s)", RepairPlaceholder, key, CodeInvalidForeignID)
+	}
+	if scoped {
+		table, column, hasColumn := strings.Cut(scope, ".")
+		var ok bool
+		if hasColumn {
+			ok = canReferencePlaceholder(gtfsSchema[table].Columns[column])
+		} else {
+			for _, schema := range gtfsSchema[table].Columns {
+				ok = ok || canReferencePlaceholder(schema)
+			}
+		}
+		if !ok {
+			return fmt.Errorf("invalid repair %q for %s (only rows in %s can have placeholders)",
+				RepairPlaceholder, key, strings.Join(placeholderTables(), ", "))
+		}
+	}
+	return nil
+}
+
+// severity returns the severity of issues with code in table.column, or "" if the check is disabled
+func (r *Rules) severity(code, table, column string) Severity {
+	scopedKey := fmt.Sprintf("%s:%s.%s", code, table, column)
+	tableKey := fmt.Sprintf("%s:%s", code, table)
+	severity, ok := defaultSeverities[code]
+	if !ok {
+		severity = SeverityError
+	}
+	if r == nil {
+		return severity
+	}
+
+	for _, key := range r.Disabled {
+		if key == code || key == tableKey || key == scopedKey {
+			return ""
+		}
+	}
+
+	if override, ok := r.Severities[scopedKey]; ok {
+		return override
+	}
+	if override, ok := r.Severities[tableKey]; ok {
+		return override
+	}
+	if override, ok := r.Severities[code]; ok {
+		return override
+	}
+	return severity
+}
+
+// repairs returns the repairs configured for issues with code in table.column, or nil if there are none
+func (r *Rules) repairs(code, table, column string) []Repair {
+	if r == nil {
+		return nil
+	}
+	if scoped, ok := r.Repairs[fmt.Sprintf("%s:%s.%s", code, table, column)]; ok {
+		return scoped
+	}
+	if scoped, ok := r.Repairs[fmt.Sprintf("%s:%s", code, table)]; ok {
+		return scoped
+	}
+	return r.Repairs[code]
+}
@@ -0,0 +1,74 @@
+package gtfs2sqlite
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]byte(`{
+		"disabled": ["missing_required_column"],
+		"severities": {"invalid_enum": "warning", "invalid_enum:routes.route_type": "error"}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"missing_required_column"}, rules.Disabled)
+	assert.Equal(t, SeverityError, rules.Severities["invalid_enum:routes.route_type"])
+
+	_, err = ParseRules([]byte(`{"repairs": {"invalid_foreign_id": ["placeholder"], "invalid_foreign_id:route_networks.network_id": ["placeholder"]}}`))
+	require.NoError(t, err)
+
+	// Scoped to a table, as the key of stop_times is two columns
+	rules, err = ParseRules([]byte(`{"severities": {"duplicate_primary_key:stop_times": "warning"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, SeverityWarning, rules.Severities["duplicate_primary_key:stop_times"])
+
+	invalid := []string{
+		`{"disabled": ["nonexistent_code"]}`,
+		`{"severities": {"invalid_enum": "fatal"}}`,
+		`{"severities": {"invalid_enum:nonexistent": "warning"}}`,
+		`{"severities": {"invalid_enum:nonexistent.route_type": "warning"}}`,
+		`{"severities": {"invalid_enum:routes.nonexistent": "warning"}}`,
+		`{"repairs": {"invalid_enum": ["guess"]}}`,
+		// Stops need a location and routes a route_type, so can't have placeholders
+		`{"repairs": {"invalid_foreign_id:stop_times.stop_id": ["placeholder"]}}`,
+		`{"repairs": {"invalid_foreign_id:trips.route_id": ["placeholder"]}}`,
+		`{"repairs": {"invalid_foreign_id:trips": ["placeholder"]}}`,
+		`{"repairs": {"invalid_enum": ["placeholder"]}}`,
+		`[]`,
+	}
+	for _, data := range invalid {
+		_, err := ParseRules([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
+
+func TestRulesSeverity(t *testing.T) {
+	var defaults *Rules
+	assert.Equal(t, SeverityError, defaults.severity(CodeInvalidEnum, "routes", "route_type"))
+
+	rules := &Rules{
+		Disabled: []string{CodeMissingRequiredColumn, CodeInvalidValue + ":stops.stop_lat"},
+		Severities: map[string]Severity{
+			CodeInvalidEnum: SeverityWarning,
+			CodeInvalidEnum + ":trips.wheelchair_accessible": SeverityError,
+		},
+	}
+	assert.Equal(t, Severity(""), rules.severity(CodeMissingRequiredColumn, "levels", "level_index"))
+	assert.Equal(t, Severity(""), rules.severity(CodeInvalidValue, "stops", "stop_lat"))
+	assert.Equal(t, SeverityError, rules.severity(CodeInvalidValue, "stops", "stop_lon"))
+	assert.Equal(t, SeverityWarning, rules.severity(CodeInvalidEnum, "routes", "route_type"))
+	assert.Equal(t, SeverityError, rules.severity(CodeInvalidEnum, "trips", "wheelchair_accessible"))
+
+	rules = &Rules{
+		Disabled:   []string{CodeRepeatedStop + ":stop_times"},
+		Severities: map[string]Severity{CodeDuplicatePrimaryKey + ":stop_times": SeverityWarning},
+		Repairs:    map[string][]Repair{CodeInvalidEnum + ":routes": {RepairDefault}},
+	}
+	assert.Equal(t, Severity(""), rules.severity(CodeRepeatedStop, "stop_times", "stop_id"))
+	assert.Equal(t, SeverityWarning, rules.severity(CodeDuplicatePrimaryKey, "stop_times", "trip_id,stop_sequence"))
+	assert.Equal(t, SeverityError, rules.severity(CodeDuplicatePrimaryKey, "stops", "stop_id"))
+	assert.Equal(t, []Repair{RepairDefault}, rules.repairs(CodeInvalidEnum, "routes", "route_type"))
+	assert.Nil(t, rules.repairs(CodeInvalidEnum, "trips", "wheelchair_accessible"))
+
+}
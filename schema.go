@@ -1,7 +1,10 @@
 package gtfs2sqlite
 
+import "slices"
+
 // NOTE: Skipped validating
-//   - foreign IDs to geojson, translations, and from calendar_dates / calendar service_ids
+//   - foreign IDs to geojson
+//   - calendar_dates.service_id, which defines a service if it isn't in calendar.txt
 
 type tableSchema struct {
 	PrimaryKey []string
@@ -12,6 +15,9 @@ type columnSchema struct {
 	TypeDescription     string
 	PresenceDescription string
 	ForeignID           *foreignIDSchema
+	Enum                *enumSchema
+	// EmptyAllowed is set for required columns where an empty value has a meaning, so only the column must be present
+	EmptyAllowed bool
 }
 
 type foreignIDSchema struct {
@@ -20,6 +26,39 @@ type foreignIDSchema struct {
 	AnyOf  []foreignIDSchema
 }
 
+type enumSchema struct {
+	Values  []int64
+	Default *int64 // The value an empty field is treated as, if any
+}
+
+func enumRange(from, to int64) []int64 {
+	var values []int64
+	for v := from; v <= to; v++ {
+		values = append(values, v)
+	}
+	return values
+}
+
+func enumDefault(value int64) *int64 {
+	return &value
+}
+
+// routeTypes includes the extended route types <https://developers.google.com/transit/gtfs/reference/extended-route-types>
+var routeTypes = slices.Concat(
+	enumRange(0, 7), enumRange(11, 12),
+	enumRange(100, 117),
+	enumRange(200, 209),
+	enumRange(400, 405),
+	enumRange(700, 716),
+	[]int64{800},
+	enumRange(900, 906),
+	[]int64{1000, 1100, 1200},
+	enumRange(1300, 1307),
+	[]int64{1400},
+	enumRange(1500, 1507),
+	enumRange(1700, 1702),
+)
+
 var gtfsSchema = map[string]tableSchema{
 	"agency": {
 		PrimaryKey: []string{"agency_id"},
@@ -47,10 +86,10 @@ var gtfsSchema = map[string]tableSchema{
 			"stop_lon":            {TypeDescription: "Longitude", PresenceDescription: "Conditionally Required"},
 			"zone_id":             {TypeDescription: "ID", PresenceDescription: "Optional"},
 			"stop_url":            {TypeDescription: "URL", PresenceDescription: "Optional"},
-			"location_type":       {TypeDescription: "Enum", PresenceDescription: "Optional"},
+			"location_type":       {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 4), Default: enumDefault(0)}, PresenceDescription: "Optional"},
 			"parent_station":      {TypeDescription: "Foreign ID referencing stops.stop_id", ForeignID: &foreignIDSchema{Table: "stops", Column: "stop_id"}, PresenceDescription: "Conditionally Required"},
 			"stop_timezone":       {TypeDescription: "Timezone", PresenceDescription: "Optional"},
-			"wheelchair_boarding": {TypeDescription: "Enum", PresenceDescription: "Optional"},
+			"wheelchair_boarding": {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 2), Default: enumDefault(0)}, PresenceDescription: "Optional"},
 			"level_id":            {TypeDescription: "Foreign ID referencing levels.level_id", ForeignID: &foreignIDSchema{Table: "levels", Column: "level_id"}, PresenceDescription: "Optional"},
 			"platform_code":       {TypeDescription: "Text", PresenceDescription: "Optional"},
 		},
@@ -68,13 +107,13 @@ var gtfsSchema = map[string]tableSchema{
 			"route_short_name":    {TypeDescription: "Text", PresenceDescription: "Conditionally Required"},
 			"route_long_name":     {TypeDescription: "Text", PresenceDescription: "Conditionally Required"},
 			"route_desc":          {TypeDescription: "Text", PresenceDescription: "Optional"},
-			"route_type":          {TypeDescription: "Enum", PresenceDescription: "Required"},
+			"route_type":          {TypeDescription: "Enum", Enum: &enumSchema{Values: routeTypes}, PresenceDescription: "Required"},
 			"route_url":           {TypeDescription: "URL", PresenceDescription: "Optional"},
 			"route_color":         {TypeDescription: "Color", PresenceDescription: "Optional"},
 			"route_text_color":    {TypeDescription: "Color", PresenceDescription: "Optional"},
 			"route_sort_order":    {TypeDescription: "Non-negative integer", PresenceDescription: "Optional"},
-			"continuous_pickup":   {TypeDescription: "Enum", PresenceDescription: "Conditionally Forbidden"},
-			"continuous_drop_off": {TypeDescription: "Enum", PresenceDescription: "Conditionally Forbidden"},
+			"continuous_pickup":   {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 3), Default: enumDefault(1)}, PresenceDescription: "Conditionally Forbidden"},
+			"continuous_drop_off": {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 3), Default: enumDefault(1)}, PresenceDescription: "Conditionally Forbidden"},
 			"network_id":          {TypeDescription: "ID", PresenceDescription: "Conditionally Forbidden"},
 		},
 	},
@@ -94,15 +133,15 @@ var gtfsSchema = map[string]tableSchema{
 			"trip_id":         {TypeDescription: "Unique ID", PresenceDescription: "Required"},
 			"trip_headsign":   {TypeDescription: "Text", PresenceDescription: "Optional"},
 			"trip_short_name": {TypeDescription: "Text", PresenceDescription: "Optional"},
-			"direction_id":    {TypeDescription: "Enum", PresenceDescription: "Optional"},
+			"direction_id":    {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1)}, PresenceDescription: "Optional"},
 			"block_id":        {TypeDescription: "ID", PresenceDescription: "Optional"},
 			"shape_id": {
 				TypeDescription:     "Foreign ID referencing shapes.shape_id",
 				ForeignID:           &foreignIDSchema{Table: "shapes", Column: "shape_id"},
 				PresenceDescription: "Conditionally Required",
 			},
-			"wheelchair_accessible": {TypeDescription: "Enum", PresenceDescription: "Optional"},
-			"bikes_allowed":         {TypeDescription: "Enum", PresenceDescription: "Optional"},
+			"wheelchair_accessible": {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 2), Default: enumDefault(0)}, PresenceDescription: "Optional"},
+			"bikes_allowed":         {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 2), Default: enumDefault(0)}, PresenceDescription: "Optional"},
 		},
 	},
 
@@ -131,12 +170,12 @@ var gtfsSchema = map[string]tableSchema{
 			"stop_headsign":                {TypeDescription: "Text", PresenceDescription: "Optional"},
 			"start_pickup_drop_off_window": {TypeDescription: "Time", PresenceDescription: "Conditionally Required"},
 			"end_pickup_drop_off_window":   {TypeDescription: "Time", PresenceDescription: "Conditionally Required"},
-			"pickup_type":                  {TypeDescription: "Enum", PresenceDescription: "Conditionally Forbidden"},
-			"drop_off_type":                {TypeDescription: "Enum", PresenceDescription: "Conditionally Forbidden"},
-			"continuous_pickup":            {TypeDescription: "Enum", PresenceDescription: "Conditionally Forbidden"},
-			"continuous_drop_off":          {TypeDescription: "Enum", PresenceDescription: "Conditionally Forbidden"},
+			"pickup_type":                  {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 3), Default: enumDefault(0)}, PresenceDescription: "Conditionally Forbidden"},
+			"drop_off_type":                {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 3), Default: enumDefault(0)}, PresenceDescription: "Conditionally Forbidden"},
+			"continuous_pickup":            {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 3), Default: enumDefault(1)}, PresenceDescription: "Conditionally Forbidden"},
+			"continuous_drop_off":          {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 3), Default: enumDefault(1)}, PresenceDescription: "Conditionally Forbidden"},
 			"shape_dist_traveled":          {TypeDescription: "Non-negative float", PresenceDescription: "Optional"},
-			"timepoint":                    {TypeDescription: "Enum", PresenceDescription: "Recommended"},
+			"timepoint":                    {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1), Default: enumDefault(1)}, PresenceDescription: "Recommended"},
 			"pickup_booking_rule_id": {
 				TypeDescription:     "Foreign ID referencing booking_rules.booking_rule_id",
 				ForeignID:           &foreignIDSchema{Table: "booking_rules", Column: "booking_rule_id"},
@@ -154,13 +193,13 @@ var gtfsSchema = map[string]tableSchema{
 		PrimaryKey: []string{"service_id"},
 		Columns: map[string]columnSchema{
 			"service_id": {TypeDescription: "Unique ID", PresenceDescription: "Required"},
-			"monday":     {TypeDescription: "Enum", PresenceDescription: "Required"},
-			"tuesday":    {TypeDescription: "Enum", PresenceDescription: "Required"},
-			"wednesday":  {TypeDescription: "Enum", PresenceDescription: "Required"},
-			"thursday":   {TypeDescription: "Enum", PresenceDescription: "Required"},
-			"friday":     {TypeDescription: "Enum", PresenceDescription: "Required"},
-			"saturday":   {TypeDescription: "Enum", PresenceDescription: "Required"},
-			"sunday":     {TypeDescription: "Enum", PresenceDescription: "Required"},
+			"monday":     {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1)}, PresenceDescription: "Required"},
+			"tuesday":    {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1)}, PresenceDescription: "Required"},
+			"wednesday":  {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1)}, PresenceDescription: "Required"},
+			"thursday":   {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1)}, PresenceDescription: "Required"},
+			"friday":     {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1)}, PresenceDescription: "Required"},
+			"saturday":   {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1)}, PresenceDescription: "Required"},
+			"sunday":     {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1)}, PresenceDescription: "Required"},
 			"start_date": {TypeDescription: "Date", PresenceDescription: "Required"},
 			"end_date":   {TypeDescription: "Date", PresenceDescription: "Required"},
 		},
@@ -174,7 +213,7 @@ var gtfsSchema = map[string]tableSchema{
 				PresenceDescription: "Required",
 			},
 			"date":           {TypeDescription: "Date", PresenceDescription: "Required"},
-			"exception_type": {TypeDescription: "Enum", PresenceDescription: "Required"},
+			"exception_type": {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(1, 2)}, PresenceDescription: "Required"},
 		},
 	},
 
@@ -184,8 +223,9 @@ var gtfsSchema = map[string]tableSchema{
 			"fare_id":        {TypeDescription: "Unique ID", PresenceDescription: "Required"},
 			"price":          {TypeDescription: "Non-negative float", PresenceDescription: "Required"},
 			"currency_type":  {TypeDescription: "Currency code", PresenceDescription: "Required"},
-			"payment_method": {TypeDescription: "Enum", PresenceDescription: "Required"},
-			"transfers":      {TypeDescription: "Enum", PresenceDescription: "Required"},
+			"payment_method": {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1)}, PresenceDescription: "Required"},
+			// Empty means unlimited transfers
+			"transfers": {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 2)}, PresenceDescription: "Required", EmptyAllowed: true},
 			"agency_id": {
 				TypeDescription:     "Foreign ID referencing agency.agency_id",
 				ForeignID:           &foreignIDSchema{Table: "agency", Column: "agency_id"},
@@ -244,11 +284,11 @@ var gtfsSchema = map[string]tableSchema{
 	},
 
 	"fare_media": {
-		PrimaryKey: []string{"fare_media"},
+		PrimaryKey: []string{"fare_media_id"},
 		Columns: map[string]columnSchema{
 			"fare_media_id":   {TypeDescription: "Unique ID", PresenceDescription: "Required"},
 			"fare_media_name": {TypeDescription: "Text", PresenceDescription: "Optional"},
-			"fare_media_type": {TypeDescription: "Enum", PresenceDescription: "Required"},
+			"fare_media_type": {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 4)}, PresenceDescription: "Required"},
 		},
 	},
 
@@ -318,8 +358,8 @@ var gtfsSchema = map[string]tableSchema{
 			},
 			"transfer_count":      {TypeDescription: "Non-zero integer", PresenceDescription: "Conditionally Forbidden"},
 			"duration_limit":      {TypeDescription: "Positive integer", PresenceDescription: "Optional"},
-			"duration_limit_type": {TypeDescription: "Enum", PresenceDescription: "Conditionally Required"},
-			"fare_transfer_type":  {TypeDescription: "Enum", PresenceDescription: "Required"},
+			"duration_limit_type": {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 3)}, PresenceDescription: "Conditionally Required"},
+			"fare_transfer_type":  {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 2)}, PresenceDescription: "Required"},
 			"fare_product_id": {
 				TypeDescription:     "Foreign ID referencing fare_products.fare_product_id",
 				ForeignID:           &foreignIDSchema{Table: "fare_products", Column: "fare_product_id"},
@@ -398,7 +438,7 @@ var gtfsSchema = map[string]tableSchema{
 			"start_time":   {TypeDescription: "Time", PresenceDescription: "Required"},
 			"end_time":     {TypeDescription: "Time", PresenceDescription: "Required"},
 			"headway_secs": {TypeDescription: "Positive integer", PresenceDescription: "Required"},
-			"exact_times":  {TypeDescription: "Enum", PresenceDescription: "Optional"},
+			"exact_times":  {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1), Default: enumDefault(0)}, PresenceDescription: "Optional"},
 		},
 	},
 
@@ -435,7 +475,7 @@ var gtfsSchema = map[string]tableSchema{
 				ForeignID:           &foreignIDSchema{Table: "trips", Column: "trip_id"},
 				PresenceDescription: "Conditionally Required",
 			},
-			"transfer_type":     {TypeDescription: "Enum", PresenceDescription: "Required"},
+			"transfer_type":     {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 5), Default: enumDefault(0)}, PresenceDescription: "Required"},
 			"min_transfer_time": {TypeDescription: "Non-negative integer", PresenceDescription: "Optional"},
 		},
 	},
@@ -454,8 +494,8 @@ var gtfsSchema = map[string]tableSchema{
 				ForeignID:           &foreignIDSchema{Table: "stops", Column: "stop_id"},
 				PresenceDescription: "Required",
 			},
-			"pathway_mode":           {TypeDescription: "Enum", PresenceDescription: "Required"},
-			"is_bidirectional":       {TypeDescription: "Enum", PresenceDescription: "Required"},
+			"pathway_mode":           {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(1, 7)}, PresenceDescription: "Required"},
+			"is_bidirectional":       {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1)}, PresenceDescription: "Required"},
 			"length":                 {TypeDescription: "Non-negative float", PresenceDescription: "Optional"},
 			"traversal_time":         {TypeDescription: "Positive integer", PresenceDescription: "Optional"},
 			"stair_count":            {TypeDescription: "Non-null integer", PresenceDescription: "Optional"},
@@ -484,7 +524,7 @@ var gtfsSchema = map[string]tableSchema{
 	},
 
 	"location_group_stops": {
-		PrimaryKey: []string{"location_group_stops"},
+		PrimaryKey: []string{"location_group_id", "stop_id"},
 		Columns: map[string]columnSchema{
 			"location_group_id": {
 				TypeDescription:     "Foreign ID referencing location_groups.location_group_id",
@@ -503,7 +543,7 @@ var gtfsSchema = map[string]tableSchema{
 		PrimaryKey: []string{"booking_rule_id"},
 		Columns: map[string]columnSchema{
 			"booking_rule_id":           {TypeDescription: "Unique ID", PresenceDescription: "Required"},
-			"booking_type":              {TypeDescription: "Enum", PresenceDescription: "Required"},
+			"booking_type":              {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 2)}, PresenceDescription: "Required"},
 			"prior_notice_duration_min": {TypeDescription: "Integer", PresenceDescription: "Conditionally Required"},
 			"prior_notice_duration_max": {TypeDescription: "Integer", PresenceDescription: "Conditionally Forbidden"},
 			"prior_notice_last_day":     {TypeDescription: "Integer", PresenceDescription: "Conditionally Required"},
@@ -511,8 +551,11 @@ var gtfsSchema = map[string]tableSchema{
 			"prior_notice_start_day":    {TypeDescription: "Integer", PresenceDescription: "Conditionally Forbidden"},
 			"prior_notice_start_time":   {TypeDescription: "Time", PresenceDescription: "Conditionally Required"},
 			"prior_notice_service_id": {
-				TypeDescription:     "Foreign ID referencing calendar.service_id",
-				ForeignID:           &foreignIDSchema{Table: "calendar", Column: "service_id"},
+				TypeDescription: "Foreign ID referencing calendar.service_id or calendar_dates.service_id",
+				ForeignID: &foreignIDSchema{AnyOf: []foreignIDSchema{
+					{Table: "calendar", Column: "service_id"},
+					{Table: "calendar_dates", Column: "service_id"},
+				}},
 				PresenceDescription: "Conditionally Forbidden",
 			},
 			"message":          {TypeDescription: "Text", PresenceDescription: "Optional"},
@@ -527,7 +570,7 @@ var gtfsSchema = map[string]tableSchema{
 	"translations": {
 		PrimaryKey: []string{"table_name", "field_name", "language", "record_id", "record_sub_id", "field_value"},
 		Columns: map[string]columnSchema{
-			"table_name":    {TypeDescription: "Enum", PresenceDescription: "Required"},
+			"table_name":    {TypeDescription: "Text", PresenceDescription: "Required"}, // An enum of table names
 			"field_name":    {TypeDescription: "Text", PresenceDescription: "Required"},
 			"language":      {TypeDescription: "Language code", PresenceDescription: "Required"},
 			"translation":   {TypeDescription: "Text or URL or Email or Phone number", PresenceDescription: "Required"},
@@ -572,12 +615,123 @@ var gtfsSchema = map[string]tableSchema{
 				PresenceDescription: "Optional",
 			},
 			"organization_name": {TypeDescription: "Text", PresenceDescription: "Required"},
-			"is_producer":       {TypeDescription: "Enum", PresenceDescription: "Optional"},
-			"is_operator":       {TypeDescription: "Enum", PresenceDescription: "Optional"},
-			"is_authority":      {TypeDescription: "Enum", PresenceDescription: "Optional"},
+			"is_producer":       {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1), Default: enumDefault(0)}, PresenceDescription: "Optional"},
+			"is_operator":       {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1), Default: enumDefault(0)}, PresenceDescription: "Optional"},
+			"is_authority":      {TypeDescription: "Enum", Enum: &enumSchema{Values: enumRange(0, 1), Default: enumDefault(0)}, PresenceDescription: "Optional"},
 			"attribution_url":   {TypeDescription: "URL", PresenceDescription: "Optional"},
 			"attribution_email": {TypeDescription: "Email", PresenceDescription: "Optional"},
 			"attribution_phone": {TypeDescription: "Phone number", PresenceDescription: "Optional"},
 		},
 	},
 }
+
+// presenceRule expresses the conditions of a Conditionally Required or Conditionally Forbidden column
+type presenceRule struct {
+	Table  string
+	Column string
+	// Forbidden rules are violated when the column is present, others when it is missing
+	Forbidden bool
+	// When is an SQL expression over rows of Table
+	When string
+	// Reason describes When, completing "required when" or "forbidden when"
+	Reason string
+}
+
+const (
+	multipleAgencies       = "(SELECT count(*) FROM agency) > 1"
+	firstOrLastStopTime    = "stop_sequence IN ((SELECT min(stop_sequence) FROM stop_times AS s WHERE s.trip_id = stop_times.trip_id), (SELECT max(stop_sequence) FROM stop_times AS s WHERE s.trip_id = stop_times.trip_id))"
+	hasPickupDropOffWindow = "(start_pickup_drop_off_window IS NOT NULL OR end_pickup_drop_off_window IS NOT NULL)"
+	hasContinuousStopping  = "(continuous_pickup IN (0, 2, 3) OR continuous_drop_off IN (0, 2, 3))"
+	translatesFeedInfo     = "table_name = 'feed_info'"
+	recordInOtherTable     = "table_name != 'feed_info'"
+)
+
+var presenceRules = []presenceRule{
+	{Table: "agency", Column: "agency_id", When: multipleAgencies, Reason: "agency.txt has multiple agencies"},
+
+	{Table: "stops", Column: "stop_name", When: "location_type IS NULL OR location_type IN (0, 1, 2)", Reason: "location_type is 0, 1 or 2"},
+	{Table: "stops", Column: "stop_lat", When: "location_type IS NULL OR location_type IN (0, 1, 2)", Reason: "location_type is 0, 1 or 2"},
+	{Table: "stops", Column: "stop_lon", When: "location_type IS NULL OR location_type IN (0, 1, 2)", Reason: "location_type is 0, 1 or 2"},
+	{Table: "stops", Column: "parent_station", When: "location_type IN (2, 3, 4)", Reason: "location_type is 2, 3 or 4"},
+	{Table: "stops", Column: "parent_station", Forbidden: true, When: "location_type = 1", Reason: "location_type is 1"},
+
+	{Table: "routes", Column: "agency_id", When: multipleAgencies, Reason: "agency.txt has multiple agencies"},
+	{Table: "routes", Column: "route_short_name", When: "route_long_name IS NULL", Reason: "route_long_name is empty"},
+	{Table: "routes", Column: "continuous_pickup", Forbidden: true,
+		When:   "route_id IN (SELECT trips.route_id FROM trips JOIN stop_times ON stop_times.trip_id = trips.trip_id WHERE " + hasPickupDropOffWindow + ")",
+		Reason: "stop_times.txt defines pickup/drop off windows for the route"},
+	{Table: "routes", Column: "continuous_drop_off", Forbidden: true,
+		When:   "route_id IN (SELECT trips.route_id FROM trips JOIN stop_times ON stop_times.trip_id = trips.trip_id WHERE " + hasPickupDropOffWindow + ")",
+		Reason: "stop_times.txt defines pickup/drop off windows for the route"},
+	{Table: "routes", Column: "network_id", Forbidden: true, When: "EXISTS (SELECT 1 FROM route_networks)", Reason: "route_networks.txt exists"},
+
+	{Table: "trips", Column: "shape_id",
+		When: "route_id IN (SELECT route_id FROM routes WHERE " + hasContinuousStopping + ") OR " +
+			"trip_id IN (SELECT trip_id FROM stop_times WHERE " + hasContinuousStopping + ")",
+		Reason: "the trip has continuous pickup or drop off"},
+
+	{Table: "stop_times", Column: "arrival_time",
+		When:   "(" + firstOrLastStopTime + " OR timepoint = 1) AND NOT " + hasPickupDropOffWindow,
+		Reason: "the stop is the first or last of the trip or a timepoint"},
+	{Table: "stop_times", Column: "arrival_time", Forbidden: true, When: hasPickupDropOffWindow, Reason: "a pickup/drop off window is defined"},
+	{Table: "stop_times", Column: "departure_time",
+		When:   "(" + firstOrLastStopTime + " OR timepoint = 1) AND NOT " + hasPickupDropOffWindow,
+		Reason: "the stop is the first or last of the trip or a timepoint"},
+	{Table: "stop_times", Column: "departure_time", Forbidden: true, When: hasPickupDropOffWindow, Reason: "a pickup/drop off window is defined"},
+	// Each pair of stop_id, location_group_id and location_id is reported once
+	{Table: "stop_times", Column: "stop_id", When: "location_group_id IS NULL AND location_id IS NULL", Reason: "location_group_id and location_id are empty"},
+	{Table: "stop_times", Column: "stop_id", Forbidden: true, When: "location_group_id IS NOT NULL OR location_id IS NOT NULL", Reason: "location_group_id or location_id is defined"},
+	{Table: "stop_times", Column: "location_group_id", Forbidden: true, When: "location_id IS NOT NULL", Reason: "location_id is defined"},
+	{Table: "stop_times", Column: "start_pickup_drop_off_window",
+		When:   "location_group_id IS NOT NULL OR location_id IS NOT NULL OR end_pickup_drop_off_window IS NOT NULL",
+		Reason: "location_group_id, location_id or end_pickup_drop_off_window is defined"},
+	{Table: "stop_times", Column: "start_pickup_drop_off_window", Forbidden: true,
+		When: "arrival_time IS NOT NULL OR departure_time IS NOT NULL", Reason: "arrival_time or departure_time is defined"},
+	{Table: "stop_times", Column: "end_pickup_drop_off_window",
+		When:   "location_group_id IS NOT NULL OR location_id IS NOT NULL OR start_pickup_drop_off_window IS NOT NULL",
+		Reason: "location_group_id, location_id or start_pickup_drop_off_window is defined"},
+	{Table: "stop_times", Column: "end_pickup_drop_off_window", Forbidden: true,
+		When: "arrival_time IS NOT NULL OR departure_time IS NOT NULL", Reason: "arrival_time or departure_time is defined"},
+	{Table: "stop_times", Column: "pickup_type", Forbidden: true,
+		When: "pickup_type IN (0, 3) AND " + hasPickupDropOffWindow, Reason: "it is 0 or 3 and a pickup/drop off window is defined"},
+	{Table: "stop_times", Column: "drop_off_type", Forbidden: true,
+		When: "drop_off_type = 0 AND " + hasPickupDropOffWindow, Reason: "it is 0 and a pickup/drop off window is defined"},
+	{Table: "stop_times", Column: "continuous_pickup", Forbidden: true,
+		When: "continuous_pickup != 1 AND " + hasPickupDropOffWindow, Reason: "it isn't 1 and a pickup/drop off window is defined"},
+	{Table: "stop_times", Column: "continuous_drop_off", Forbidden: true,
+		When: "continuous_drop_off != 1 AND " + hasPickupDropOffWindow, Reason: "it isn't 1 and a pickup/drop off window is defined"},
+
+	{Table: "fare_attributes", Column: "agency_id", When: multipleAgencies, Reason: "agency.txt has multiple agencies"},
+
+	{Table: "timeframe", Column: "start_time", When: "end_time IS NOT NULL", Reason: "end_time is defined"},
+	{Table: "timeframe", Column: "end_time", When: "start_time IS NOT NULL", Reason: "start_time is defined"},
+
+	{Table: "fare_transfer_rules", Column: "transfer_count", When: "from_leg_group_id IS to_leg_group_id", Reason: "from_leg_group_id equals to_leg_group_id"},
+	{Table: "fare_transfer_rules", Column: "transfer_count", Forbidden: true, When: "from_leg_group_id IS NOT to_leg_group_id", Reason: "from_leg_group_id differs from to_leg_group_id"},
+	{Table: "fare_transfer_rules", Column: "duration_limit_type", When: "duration_limit IS NOT NULL", Reason: "duration_limit is defined"},
+	{Table: "fare_transfer_rules", Column: "duration_limit_type", Forbidden: true, When: "duration_limit IS NULL", Reason: "duration_limit is empty"},
+
+	{Table: "transfers", Column: "from_stop_id", When: "transfer_type IN (1, 2, 3)", Reason: "transfer_type is 1, 2 or 3"},
+	{Table: "transfers", Column: "to_stop_id", When: "transfer_type IN (1, 2, 3)", Reason: "transfer_type is 1, 2 or 3"},
+	{Table: "transfers", Column: "from_trip_id", When: "transfer_type IN (4, 5)", Reason: "transfer_type is 4 or 5"},
+	{Table: "transfers", Column: "to_trip_id", When: "transfer_type IN (4, 5)", Reason: "transfer_type is 4 or 5"},
+
+	{Table: "booking_rules", Column: "prior_notice_duration_min", When: "booking_type = 1", Reason: "booking_type is 1"},
+	{Table: "booking_rules", Column: "prior_notice_duration_max", Forbidden: true, When: "booking_type IN (0, 2)", Reason: "booking_type is 0 or 2"},
+	{Table: "booking_rules", Column: "prior_notice_last_day", When: "booking_type = 2", Reason: "booking_type is 2"},
+	{Table: "booking_rules", Column: "prior_notice_last_time", When: "prior_notice_last_day IS NOT NULL", Reason: "prior_notice_last_day is defined"},
+	{Table: "booking_rules", Column: "prior_notice_start_day", Forbidden: true,
+		When:   "booking_type = 0 OR (booking_type = 1 AND prior_notice_duration_max IS NOT NULL)",
+		Reason: "booking_type is 0, or booking_type is 1 and prior_notice_duration_max is defined"},
+	{Table: "booking_rules", Column: "prior_notice_start_time", When: "prior_notice_start_day IS NOT NULL", Reason: "prior_notice_start_day is defined"},
+	{Table: "booking_rules", Column: "prior_notice_service_id", Forbidden: true, When: "booking_type != 2", Reason: "booking_type isn't 2"},
+
+	{Table: "translations", Column: "record_id", When: recordInOtherTable + " AND field_value IS NULL", Reason: "field_value is empty"},
+	{Table: "translations", Column: "record_id", Forbidden: true,
+		When: translatesFeedInfo + " OR field_value IS NOT NULL", Reason: "table_name is feed_info or field_value is defined"},
+	{Table: "translations", Column: "record_sub_id", When: "table_name = 'stop_times' AND record_id IS NOT NULL", Reason: "table_name is stop_times and record_id is defined"},
+	{Table: "translations", Column: "record_sub_id", Forbidden: true,
+		When: translatesFeedInfo + " OR field_value IS NOT NULL", Reason: "table_name is feed_info or field_value is defined"},
+	{Table: "translations", Column: "field_value", When: recordInOtherTable + " AND record_id IS NULL", Reason: "record_id is empty"},
+	{Table: "translations", Column: "field_value", Forbidden: true, When: translatesFeedInfo, Reason: "table_name is feed_info"},
+}
@@ -0,0 +1,115 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"fmt"
+	"math"
+)
+
+// maxSpeeds is the fastest plausible speed in km/h for each basic route_type, and for each hundred of extended route
+// types. Air services (1100) aren't checked.
+var maxSpeeds = map[int64]float64{
+	0:    100, // Tram
+	1:    150, // Subway
+	2:    350, // Rail
+	3:    150, // Bus
+	4:    80,  // Ferry
+	5:    30,  // Cable tram
+	6:    50,  // Aerial lift
+	7:    50,  // Funicular
+	11:   150, // Trolleybus
+	12:   150, // Monorail
+	100:  350, // Railway
+	200:  150, // Coach
+	400:  150, // Urban railway
+	700:  150, // Bus
+	800:  150, // Trolleybus
+	900:  100, // Tram
+	1000: 80,  // Water transport
+	1200: 80,  // Ferry
+	1300: 50,  // Aerial lift
+	1400: 50,  // Funicular
+	1500: 150, // Taxi
+	1700: 150, // Miscellaneous
+}
+
+func maxSpeed(routeType int64) (float64, bool) {
+	if routeType >= 100 {
+		routeType = routeType / 100 * 100
+	}
+	speed, ok := maxSpeeds[routeType]
+	return speed, ok
+}
+
+// minTravelSeconds is the time travel between stops is assumed to take at least, as times are usually given to the
+// minute. A long distance covered in zero minutes is flagged as if it took one.
+const minTravelSeconds = 60
+
+// validateTravelSpeed checks the speed implied by the distance between consecutive timed stops of a trip and the time
+// taken to travel between them is plausible for the type of route. Stops without times are skipped.
+func (v *validator) validateTravelSpeed() error {
+	query := `
+SELECT * FROM (
+	SELECT stop_times.rowid AS rowid, stop_times.*,
+		routes.route_type AS __gtfs2sqlite_route_type,
+		stops.stop_lat AS __gtfs2sqlite_lat,
+		stops.stop_lon AS __gtfs2sqlite_lon,
+		coalesce(arrival_time, departure_time) AS __gtfs2sqlite_time,
+		lag(stop_times.stop_id) OVER trip AS __gtfs2sqlite_previous_stop,
+		lag(stops.stop_lat) OVER trip AS __gtfs2sqlite_previous_lat,
+		lag(stops.stop_lon) OVER trip AS __gtfs2sqlite_previous_lon,
+		lag(coalesce(departure_time, arrival_time)) OVER trip AS __gtfs2sqlite_previous_time
+	FROM stop_times
+	JOIN stops ON stops.stop_id = stop_times.stop_id
+	JOIN trips ON trips.trip_id = stop_times.trip_id
+	JOIN routes ON routes.route_id = trips.route_id
+	WHERE typeof(coalesce(arrival_time, departure_time)) = 'integer'
+		AND typeof(coalesce(departure_time, arrival_time)) = 'integer'
+		AND typeof(stops.stop_lat) IN ('integer', 'real') AND typeof(stops.stop_lon) IN ('integer', 'real')
+		AND typeof(routes.route_type) = 'integer'
+	WINDOW trip AS (PARTITION BY stop_times.trip_id ORDER BY stop_times.stop_sequence)
+) WHERE __gtfs2sqlite_previous_time IS NOT NULL`
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		routeType := stmt.GetInt64("__gtfs2sqlite_route_type")
+		limit, ok := maxSpeed(routeType)
+		if !ok {
+			return nil
+		}
+
+		km := distanceKm(stmt.GetFloat("__gtfs2sqlite_previous_lat"), stmt.GetFloat("__gtfs2sqlite_previous_lon"),
+			stmt.GetFloat("__gtfs2sqlite_lat"), stmt.GetFloat("__gtfs2sqlite_lon"))
+		seconds := stmt.GetInt64("__gtfs2sqlite_time") - stmt.GetInt64("__gtfs2sqlite_previous_time")
+		speed := km / float64(max(seconds, minTravelSeconds)) * 3600
+		if speed <= limit {
+			return nil
+		}
+
+		row := rowValues("stop_times", stmt)
+		v.reject(ValidationIssue{
+			Code: CodeImplausibleSpeed,
+			Message: fmt.Sprintf("trip %s in stop_times.txt travels %.1f km from %s to %s in %d minute(s) "+
+				"(%.0f km/h, expected at most %.0f km/h for route_type %d)",
+				row["trip_id"], km, stmt.GetText("__gtfs2sqlite_previous_stop"), row["stop_id"], seconds/60,
+				speed, limit, routeType),
+			Table:  "stop_times",
+			Column: "stop_id",
+			RowID:  stmt.GetInt64("rowid"),
+			Value:  row["stop_id"],
+			Row:    row,
+		})
+		return nil
+	})
+}
+
+const earthRadiusKm = 6371
+
+// distanceKm is the great-circle distance between two points
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Pow(math.Sin(dLat/2), 2) + math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,61 @@
+package gtfs2sqlite
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidateTravelSpeed(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		// Furnace Creek Resort is moved about 400 km north
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon\n" +
+			"FUR_CREEK_RES,Furnace Creek Resort (Demo),40.0,-117.133162\n" +
+			"BEATTY_AIRPORT,Nye County Airport (Demo),36.868446,-116.784582\n" +
+			"BULLFROG,Bullfrog (Demo),36.88108,-116.81797\n" +
+			"STAGECOACH,Stagecoach Hotel & Casino (Demo),36.915682,-116.751677\n" +
+			"NADAV,North Ave / D Ave N (Demo),36.914893,-116.76821\n" +
+			"NANAA,North Ave / N A Ave (Demo),36.914944,-116.761472\n" +
+			"DADAN,Doing Ave / D Ave N (Demo),36.909489,-116.768242\n" +
+			"EMSI,E Main St / S Irving St (Demo),36.905697,-116.76218\n" +
+			"AMV,Amargosa Valley (Demo),36.641496,-116.40094\n",
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+			// Plausible
+			"STBA,6:00:00,6:00:00,STAGECOACH,1\n" +
+			"STBA,6:20:00,6:20:00,BEATTY_AIRPORT,2\n" +
+			// Zero minutes
+			"AB1,8:00:00,8:00:00,BEATTY_AIRPORT,1\n" +
+			"AB1,8:00:00,8:00:00,BULLFROG,2\n" +
+			// Too far
+			"BFC1,8:20:00,8:20:00,BULLFROG,1\n" +
+			"BFC1,9:20:00,9:20:00,FUR_CREEK_RES,2\n",
+	})
+
+	issues, err := Validate(input, nil)
+	require.NoError(t, err)
+
+	var got []string
+	for _, issue := range issues {
+		if issue.Code == CodeImplausibleSpeed {
+			assert.Equal(t, SeverityWarning, issue.Severity)
+			got = append(got, issue.Message)
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"trip AB1 in stop_times.txt travels 3.3 km from BEATTY_AIRPORT to BULLFROG in 0 minute(s) (197 km/h, expected at most 150 km/h for route_type 3)",
+		"trip BFC1 in stop_times.txt travels 347.9 km from BULLFROG to FUR_CREEK_RES in 60 minute(s) (348 km/h, expected at most 150 km/h for route_type 3)",
+	}, got)
+}
+
+func TestMaxSpeed(t *testing.T) {
+	speed, ok := maxSpeed(3)
+	assert.True(t, ok)
+	assert.Equal(t, 150.0, speed)
+
+	speed, ok = maxSpeed(109)
+	assert.True(t, ok)
+	assert.Equal(t, 350.0, speed)
+
+	_, ok = maxSpeed(1100)
+	assert.False(t, ok)
+}
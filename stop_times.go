@@ -0,0 +1,137 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"fmt"
+)
+
+// Stop times are compared within a trip in the order of their stop_sequence, so the order they're listed in doesn't
+// matter. Equal stop_sequences are duplicate primary keys. Values that aren't numbers are skipped, as they have
+// already been reported as invalid.
+
+func (v *validator) validateArrivalBeforeDeparture() error {
+	query := `
+SELECT rowid, * FROM stop_times
+WHERE typeof(arrival_time) = 'integer' AND typeof(departure_time) = 'integer' AND arrival_time > departure_time`
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		row := rowValues("stop_times", stmt)
+		v.reject(ValidationIssue{
+			Code: CodeArrivalAfterDeparture,
+			Message: fmt.Sprintf("arrival_time %s in stop_times.txt is after departure_time %s",
+				row["arrival_time"], row["departure_time"]),
+			Table:  "stop_times",
+			Column: "departure_time",
+			RowID:  stmt.GetInt64("rowid"),
+			Value:  row["departure_time"],
+			Row:    row,
+		})
+		return nil
+	})
+}
+
+// validateStopTimesIncrease checks each stop is reached no earlier than the trip left the previous timed stop.
+// Stops without times are skipped.
+func (v *validator) validateStopTimesIncrease() error {
+	query := `
+SELECT * FROM (
+	SELECT rowid, *,
+		coalesce(arrival_time, departure_time) AS __gtfs2sqlite_time,
+		lag(coalesce(departure_time, arrival_time)) OVER (PARTITION BY trip_id ORDER BY stop_sequence) AS __gtfs2sqlite_previous
+	FROM stop_times
+	WHERE typeof(coalesce(arrival_time, departure_time)) = 'integer'
+		AND typeof(coalesce(departure_time, arrival_time)) = 'integer'
+) WHERE __gtfs2sqlite_time < __gtfs2sqlite_previous`
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		row := rowValues("stop_times", stmt)
+		column := "arrival_time"
+		if row[column] == "" {
+			column = "departure_time"
+		}
+		v.reject(ValidationIssue{
+			Code: CodeDecreasingStopTime,
+			Message: fmt.Sprintf("%s %s in stop_times.txt is before the trip %s leaves the previous stop at %s",
+				column, row[column], row["trip_id"], formatValue(timeKind, stmt.GetInt64("__gtfs2sqlite_previous"))),
+			Table:  "stop_times",
+			Column: column,
+			RowID:  stmt.GetInt64("rowid"),
+			Value:  row[column],
+			Row:    row,
+		})
+		return nil
+	})
+}
+
+func (v *validator) validateShapeDistanceIncreases() error {
+	query := `
+SELECT * FROM (
+	SELECT rowid, *,
+		lag(shape_dist_traveled) OVER (PARTITION BY trip_id ORDER BY stop_sequence) AS __gtfs2sqlite_previous
+	FROM stop_times WHERE typeof(shape_dist_traveled) IN ('integer', 'real')
+) WHERE shape_dist_traveled < __gtfs2sqlite_previous`
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		row := rowValues("stop_times", stmt)
+		v.reject(ValidationIssue{
+			Code: CodeDecreasingShapeDistance,
+			Message: fmt.Sprintf("shape_dist_traveled %s in stop_times.txt is less than %s at the previous stop of trip %s",
+				row["shape_dist_traveled"], formatValue(realKind, stmt.GetFloat("__gtfs2sqlite_previous")), row["trip_id"]),
+			Table:  "stop_times",
+			Column: "shape_dist_traveled",
+			RowID:  stmt.GetInt64("rowid"),
+			Value:  row["shape_dist_traveled"],
+			Row:    row,
+		})
+		return nil
+	})
+}
+
+// validateRepeatedStops checks a trip doesn't visit the same stop twice in a row
+func (v *validator) validateRepeatedStops() error {
+	query := `
+SELECT * FROM (
+	SELECT rowid, *, lag(stop_id) OVER (PARTITION BY trip_id ORDER BY stop_sequence) AS __gtfs2sqlite_previous
+	FROM stop_times
+) WHERE stop_id = __gtfs2sqlite_previous`
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		row := rowValues("stop_times", stmt)
+		v.reject(ValidationIssue{
+			Code:    CodeRepeatedStop,
+			Message: fmt.Sprintf("%s in stop_times.txt is visited twice in a row by trip %s", row["stop_id"], row["trip_id"]),
+			Table:   "stop_times",
+			Column:  "stop_id",
+			RowID:   stmt.GetInt64("rowid"),
+			Value:   row["stop_id"],
+			Row:     row,
+		})
+		return nil
+	})
+}
+
+// validateTripStopTimeCount checks every trip has at least two stop times, as a trip with fewer can't take anyone
+// anywhere
+func (v *validator) validateTripStopTimeCount() error {
+	query := `
+SELECT * FROM (
+	SELECT rowid, *, (SELECT count(*) FROM stop_times WHERE stop_times.trip_id = trips.trip_id) AS __gtfs2sqlite_count
+	FROM trips
+) WHERE __gtfs2sqlite_count < 2`
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		row := rowValues("trips", stmt)
+		v.reject(ValidationIssue{
+			Code: CodeTooFewStopTimes,
+			Message: fmt.Sprintf("%s in trips.txt has %d stop time(s) (expected at least 2)",
+				row["trip_id"], stmt.GetInt64("__gtfs2sqlite_count")),
+			Table:  "trips",
+			Column: "trip_id",
+			RowID:  stmt.GetInt64("rowid"),
+			Value:  row["trip_id"],
+			Row:    row,
+		})
+		return nil
+	})
+}
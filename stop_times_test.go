@@ -0,0 +1,51 @@
+package gtfs2sqlite
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidateStopTimes(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence,shape_dist_traveled\n" +
+			// Valid
+			"STBA,6:00:00,6:00:00,STAGECOACH,1,0\n" +
+			"STBA,6:20:00,6:20:00,BEATTY_AIRPORT,2,5\n" +
+			// Listed out of order, which is fine, and arrives before departing
+			"CITY1,6:05:00,6:07:00,NANAA,2,\n" +
+			"CITY1,6:00:00,6:00:00,STAGECOACH,1,\n" +
+			"CITY1,6:14:00,6:12:00,NADAV,3,\n" +
+			// Goes back in time and in distance, and stays at the same stop
+			"CITY2,6:28:00,6:30:00,EMSI,1,2\n" +
+			"CITY2,6:25:00,6:37:00,DADAN,2,1\n" +
+			"CITY2,,,DADAN,3,\n" +
+			"CITY2,6:49:00,6:51:00,NANAA,4,3\n" +
+			// Only one stop
+			"AB1,8:00:00,8:00:00,BEATTY_AIRPORT,1,\n",
+	})
+
+	issues, err := Validate(input, nil)
+	require.ErrorIs(t, err, ErrInvalidInput)
+
+	got := make(map[string][]string)
+	for _, issue := range issues {
+		got[issue.Code] = append(got[issue.Code], issue.Message)
+	}
+	assert.Equal(t, []string{"arrival_time 06:14:00 in stop_times.txt is after departure_time 06:12:00"}, got[CodeArrivalAfterDeparture])
+	assert.Equal(t, []string{"arrival_time 06:25:00 in stop_times.txt is before the trip CITY2 leaves the previous stop at 06:30:00"}, got[CodeDecreasingStopTime])
+	assert.Equal(t, []string{"shape_dist_traveled 1 in stop_times.txt is less than 2 at the previous stop of trip CITY2"}, got[CodeDecreasingShapeDistance])
+	assert.Equal(t, []string{"DADAN in stop_times.txt is visited twice in a row by trip CITY2"}, got[CodeRepeatedStop])
+	assert.Contains(t, got[CodeTooFewStopTimes], "AB1 in trips.txt has 1 stop time(s) (expected at least 2)")
+	assert.Contains(t, got[CodeTooFewStopTimes], "AB2 in trips.txt has 0 stop time(s) (expected at least 2)")
+	assert.NotContains(t, got[CodeTooFewStopTimes], "STBA in trips.txt has 2 stop time(s) (expected at least 2)")
+
+	for _, issue := range issues {
+		switch issue.Code {
+		case CodeRepeatedStop, CodeTooFewStopTimes:
+			assert.Equal(t, SeverityWarning, issue.Severity)
+		case CodeArrivalAfterDeparture, CodeDecreasingStopTime, CodeDecreasingShapeDistance:
+			assert.Equal(t, SeverityError, issue.Severity, issue.Message)
+		}
+	}
+}
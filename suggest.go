@@ -0,0 +1,131 @@
+package gtfs2sqlite
+
+import (
+	"cmp"
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSuggestions is how many existing IDs are suggested for an invalid foreign ID
+const maxSuggestions = 3
+
+// idSuggester suggests existing IDs close to invalid foreign IDs. The existing IDs are only read once an invalid
+// foreign ID is found.
+type idSuggester struct {
+	db       *sqlite.Conn
+	query    string // selects the existing IDs
+	ids      []string
+	loaded   bool
+	previous map[string][]string // by invalid value
+}
+
+func newIDSuggester(db *sqlite.Conn, query string) *idSuggester {
+	return &idSuggester{db: db, query: query, previous: make(map[string][]string)}
+}
+
+// suggest returns the existing IDs that differ from value only in case and surrounding whitespace or, if there are
+// none, the closest by edit distance. Short IDs are only suggested if they differ in case or whitespace, as almost
+// any other short ID would be close.
+func (s *idSuggester) suggest(value string) ([]string, error) {
+	if suggestions, ok := s.previous[value]; ok {
+		return suggestions, nil
+	}
+
+	if !s.loaded {
+		err := sqlitex.Exec(s.db, s.query, func(stmt *sqlite.Stmt) error {
+			s.ids = append(s.ids, stmt.ColumnText(0))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.loaded = true
+	}
+
+	normalized := normalizeID(value)
+	var matches []string
+	for _, id := range s.ids {
+		if normalizeID(id) == normalized {
+			matches = append(matches, id)
+		}
+	}
+
+	if len(matches) == 0 {
+		type candidate struct {
+			id       string
+			distance int
+		}
+		var candidates []candidate
+		maxDistance := min(2, utf8.RuneCountInString(value)/4)
+		for _, id := range s.ids {
+			if distance := editDistance(value, id, maxDistance); distance <= maxDistance {
+				candidates = append(candidates, candidate{id, distance})
+			}
+		}
+		slices.SortFunc(candidates, func(a, b candidate) int {
+			return cmp.Or(cmp.Compare(a.distance, b.distance), cmp.Compare(a.id, b.id))
+		})
+		for _, c := range candidates {
+			matches = append(matches, c.id)
+		}
+	} else {
+		slices.Sort(matches)
+	}
+
+	if len(matches) > maxSuggestions {
+		matches = matches[:maxSuggestions]
+	}
+	s.previous[value] = matches
+	return matches, nil
+}
+
+func normalizeID(id string) string {
+	return strings.ToLower(strings.TrimSpace(id))
+}
+
+// editDistance is the Levenshtein distance between a and b, or limit+1 if it is greater than limit
+func editDistance(a, b string, limit int) int {
+	ar, br := []rune(a), []rune(b)
+	if len(ar)-len(br) > limit || len(br)-len(ar) > limit {
+		return limit + 1
+	}
+
+	previous := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(br); j++ {
+			substitution := previous[j-1]
+			if ar[i-1] != br[j-1] {
+				substitution++
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, substitution)
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		previous, current = current, previous
+	}
+	return min(previous[len(br)], limit+1)
+}
+
+// formatSuggestions completes an invalid foreign ID message with the suggested IDs, if any
+func formatSuggestions(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	var quoted []string
+	for _, suggestion := range suggestions {
+		quoted = append(quoted, fmt.Sprintf("%q", suggestion))
+	}
+	return fmt.Sprintf(" (did you mean %s?)", strings.Join(quoted, " or "))
+}
@@ -0,0 +1,82 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("1234", "1234", 2))
+	assert.Equal(t, 1, editDistance("1234a", "1234", 2))
+	assert.Equal(t, 1, editDistance("1234", "1244", 2))
+	assert.Equal(t, 3, editDistance("kitten", "sitting", 3))
+	assert.Equal(t, 2, editDistance("kitten", "sitting", 1))
+	assert.Equal(t, 2, editDistance("abc", "abcdefgh", 1))
+	assert.Equal(t, 1, editDistance("héllo", "hello", 1))
+}
+
+func TestValidateForeignIDSuggestions(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"routes.txt": "route_id,agency_id,route_short_name,route_long_name,route_type\n" +
+			"AB,DTA,10,Airport - Bullfrog,3\n" +
+			"BFC,DTA,20,Bullfrog - Furnace Creek Resort,3\n" +
+			"STBA,DTA,30,Stagecoach - Airport Shuttle,3\n" +
+			"CITY,DTA,40,City,3\n" +
+			"city,DTA,41,City,3\n" +
+			"AAMV,DTA,50,Airport - Amargosa Valley,3\n",
+		"trips.txt": "route_id,service_id,trip_id\n" +
+			"\"ab \",FULLW,AB1\n" +
+			"STBAA,FULLW,AB2\n" +
+			"STBA,FULLW,STBA\n" +
+			"City,FULLW,CITY1\n" +
+			"NOWHERE,FULLW,CITY2\n" +
+			"BFC,FULLW,BFC1\n" +
+			"BFC,FULLW,BFC2\n" +
+			"AAMV,WE,AAMV1\n" +
+			"AAMV,WE,AAMV2\n" +
+			"AAMV,WE,AAMV3\n" +
+			"AAMV,WE,AAMV4\n",
+	})
+
+	issues, err := Validate(input, nil)
+	require.ErrorIs(t, err, ErrInvalidInput)
+	var suggestions [][]string
+	var messages []string
+	for _, issue := range issues {
+		if issue.Code == CodeInvalidForeignID {
+			suggestions = append(suggestions, issue.Suggestions)
+			messages = append(messages, issue.Message)
+		}
+	}
+	assert.Equal(t, [][]string{{"AB"}, {"STBA"}, {"CITY", "city"}, nil}, suggestions)
+	assert.Equal(t, []string{
+		`ab  in trips.txt is not a valid route_id (did you mean "AB"?)`,
+		`STBAA in trips.txt is not a valid route_id (did you mean "STBA"?)`,
+		`City in trips.txt is not a valid route_id (did you mean "CITY" or "city"?)`,
+		`NOWHERE in trips.txt is not a valid route_id`,
+	}, messages)
+
+	t.Run("rewrite", func(t *testing.T) {
+		rules := &Rules{Repairs: map[string][]Repair{CodeInvalidForeignID: {RepairRewrite}}}
+		outDir := testTempdir(t)
+		_, err := Import(input, outDir+"/imported.db", &ImportOpts{ForceValid: true, Rules: rules})
+		require.NoError(t, err)
+
+		conn, err := sqlite.OpenConn(outDir+"/imported.db", sqlite.SQLITE_OPEN_READONLY)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		// Ambiguous references and those without suggestions are deleted
+		routes := make(map[string]string)
+		err = sqlitex.Exec(conn, "SELECT trip_id, route_id FROM trips WHERE trip_id IN ('AB1', 'AB2', 'CITY1', 'CITY2')",
+			func(stmt *sqlite.Stmt) error {
+				routes[stmt.GetText("trip_id")] = stmt.GetText("route_id")
+				return nil
+			})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"AB1": "AB", "AB2": "STBA"}, routes)
+	})
+}
@@ -0,0 +1,139 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"fmt"
+	"math"
+	"time"
+)
+
+// maxSolarOffsetHours is how far the UTC offset of a stop's timezone can be from mean solar time at its longitude.
+// Timezones follow borders rather than longitude, so this only catches stops in the wrong part of the world.
+const maxSolarOffsetHours = 3.5
+
+// validateAgencyTimezones checks every agency has the same agency_timezone as the first
+func (v *validator) validateAgencyTimezones() error {
+	query := `
+SELECT rowid, * FROM agency
+WHERE agency_timezone IS NOT (SELECT agency_timezone FROM agency ORDER BY rowid LIMIT 1)`
+
+	var first string
+	err := sqlitex.Exec(v.db, "SELECT agency_id, agency_timezone FROM agency ORDER BY rowid LIMIT 1",
+		func(stmt *sqlite.Stmt) error {
+			first = fmt.Sprintf("%s of %s", stmt.GetText("agency_timezone"), stmt.GetText("agency_id"))
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		row := rowValues("agency", stmt)
+		v.reject(ValidationIssue{
+			Code: CodeInconsistentTimezone,
+			Message: fmt.Sprintf("agency_timezone %s of %s in agency.txt differs from %s, but every agency must have the same timezone",
+				row["agency_timezone"], row["agency_id"], first),
+			Table:  "agency",
+			Column: "agency_timezone",
+			RowID:  stmt.GetInt64("rowid"),
+			Value:  row["agency_timezone"],
+			Row:    row,
+		})
+		return nil
+	})
+}
+
+// validateChildStopTimezones checks stops with a parent station don't declare a different stop_timezone to it, as
+// the spec says they inherit the parent's. A parent without a stop_timezone has the timezone of the agency.
+func (v *validator) validateChildStopTimezones() error {
+	query := `
+SELECT * FROM (
+	SELECT stops.rowid AS rowid, stops.*,
+		coalesce(parent.stop_timezone, (SELECT agency_timezone FROM agency ORDER BY rowid LIMIT 1))
+			AS __gtfs2sqlite_parent_timezone
+	FROM stops JOIN stops AS parent ON parent.stop_id = stops.parent_station
+	WHERE stops.stop_timezone IS NOT NULL
+)
+WHERE __gtfs2sqlite_parent_timezone IS NOT NULL AND stop_timezone != __gtfs2sqlite_parent_timezone`
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		row := rowValues("stops", stmt)
+		v.reject(ValidationIssue{
+			Code: CodeInconsistentTimezone,
+			Message: fmt.Sprintf("stop_timezone %s of %s in stops.txt differs from %s of its parent station %s",
+				row["stop_timezone"], row["stop_id"], stmt.GetText("__gtfs2sqlite_parent_timezone"), row["parent_station"]),
+			Table:  "stops",
+			Column: "stop_timezone",
+			RowID:  stmt.GetInt64("rowid"),
+			Value:  row["stop_timezone"],
+			Row:    row,
+		})
+		return nil
+	})
+}
+
+// validateStopTimezoneLocations checks the timezone of each stop roughly matches its longitude, by comparing the
+// timezone's UTC offsets over the year of the reference date with mean solar time
+func (v *validator) validateStopTimezoneLocations() error {
+	reference := v.opts.referenceDate
+	if reference.IsZero() {
+		reference = time.Now()
+	}
+
+	offsets := make(map[string][]float64)
+	zoneOffsets := func(name string) []float64 {
+		if cached, ok := offsets[name]; ok {
+			return cached
+		}
+		var out []float64
+		// Invalid timezones have already been reported
+		if isTimezone(name) {
+			loc, _ := time.LoadLocation(name)
+			for _, month := range []time.Month{time.January, time.July} {
+				_, offset := time.Date(reference.Year(), month, 1, 0, 0, 0, 0, loc).Zone()
+				out = append(out, float64(offset)/3600)
+			}
+		}
+		offsets[name] = out
+		return out
+	}
+
+	query := `
+SELECT stops.rowid AS rowid, stops.*, coalesce(stops.stop_timezone, parent.stop_timezone,
+	(SELECT agency_timezone FROM agency ORDER BY rowid LIMIT 1)) AS __gtfs2sqlite_timezone
+FROM stops LEFT JOIN stops AS parent ON parent.stop_id = stops.parent_station
+WHERE typeof(stops.stop_lon) IN ('integer', 'real')`
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		timezone := stmt.GetText("__gtfs2sqlite_timezone")
+		zone := zoneOffsets(timezone)
+		if len(zone) == 0 {
+			return nil
+		}
+
+		solar := stmt.GetFloat("stop_lon") / 15
+		closest := math.Inf(1)
+		for _, offset := range zone {
+			// Wrap around the date line
+			diff := math.Mod(math.Abs(offset-solar), 24)
+			closest = min(closest, diff, 24-diff)
+		}
+		if closest <= maxSolarOffsetHours {
+			return nil
+		}
+
+		row := rowValues("stops", stmt)
+		v.reject(ValidationIssue{
+			Code: CodeStopOutsideTimezone,
+			Message: fmt.Sprintf("%s in stops.txt at longitude %s is %.1f hours from the UTC offset of its timezone %s",
+				row["stop_id"], row["stop_lon"], closest, timezone),
+			Table:  "stops",
+			Column: "stop_lon",
+			RowID:  stmt.GetInt64("rowid"),
+			Value:  row["stop_lon"],
+			Row:    row,
+		})
+		return nil
+	})
+}
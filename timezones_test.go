@@ -0,0 +1,97 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"slices"
+	"testing"
+)
+
+func TestValidateTimezones(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"agency.txt": "agency_id,agency_name,agency_url,agency_timezone\n" +
+			"DTA,Demo Transit Authority,http://google.com,America/Los_Angeles\n" +
+			"NYC,New York Transit Authority,http://google.com,America/New_York\n" +
+			"OTHER,Other Transit Authority,http://google.com,America/Los_Angeles\n",
+		"routes.txt": "route_id,agency_id,route_short_name,route_long_name,route_type\n" +
+			"AB,DTA,10,Airport - Bullfrog,3\n" +
+			"BFC,DTA,20,Bullfrog - Furnace Creek Resort,3\n" +
+			"STBA,DTA,30,Stagecoach - Airport Shuttle,3\n" +
+			"CITY,NYC,40,City,3\n" +
+			"AAMV,OTHER,50,Airport - Amargosa Valley,3\n",
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station,stop_timezone\n" +
+			"FUR_CREEK_RES,Furnace Creek Resort (Demo),36.425288,-117.133162,,,\n" +
+			"BEATTY_AIRPORT_STATION,Nye County Airport Station (Demo),36.868446,-116.784582,1,,America/Los_Angeles\n" +
+			"BEATTY_AIRPORT,Nye County Airport (Demo),36.868446,-116.784582,,BEATTY_AIRPORT_STATION,America/Denver\n" +
+			"BULLFROG,Bullfrog (Demo),36.88108,-116.81797,,,America/Phoenix\n" +
+			"STAGECOACH,Stagecoach Hotel & Casino (Demo),36.915682,-116.751677,,,Europe/London\n" +
+			"NADAV,North Ave / D Ave N (Demo),36.914893,-116.76821,,,Asia/Tokyo\n" +
+			"NANAA,North Ave / N A Ave (Demo),36.914944,-116.761472,,,\n" +
+			"DADAN,Doing Ave / D Ave N (Demo),36.909489,-116.768242,,,\n" +
+			"EMSI,E Main St / S Irving St (Demo),36.905697,-116.76218,,,\n" +
+			// Across the date line from Los Angeles, but only two hours from solar time
+			"AMV,Amargosa Valley (Demo),-14.29,-170.7,,,Pacific/Pago_Pago\n",
+	})
+
+	timezoneIssues := func(issues []ValidationIssue) []string {
+		var out []string
+		for _, issue := range issues {
+			if slices.Contains([]string{CodeInconsistentTimezone, CodeStopOutsideTimezone}, issue.Code) {
+				out = append(out, issue.Message)
+			}
+		}
+		return out
+	}
+
+	issues, err := Validate(input, &ValidateOpts{ReferenceDate: sampleDate})
+	require.ErrorIs(t, err, ErrInvalidInput)
+	assert.ElementsMatch(t, []string{
+		"agency_timezone America/New_York of NYC in agency.txt differs from America/Los_Angeles of DTA, but every agency must have the same timezone",
+		"stop_timezone America/Denver of BEATTY_AIRPORT in stops.txt differs from America/Los_Angeles of its parent station BEATTY_AIRPORT_STATION",
+		"STAGECOACH in stops.txt at longitude -116.751677 is 7.8 hours from the UTC offset of its timezone Europe/London",
+		"NADAV in stops.txt at longitude -116.76821 is 7.2 hours from the UTC offset of its timezone Asia/Tokyo",
+	}, timezoneIssues(issues))
+
+	t.Run("fix", func(t *testing.T) {
+		outDir := testTempdir(t)
+		_, err := Import(input, outDir+"/imported.db", &ImportOpts{ForceValid: true, ReferenceDate: sampleDate})
+		require.ErrorIs(t, err, ErrInvalidInput)
+
+		issues, err := Import(input, outDir+"/imported.db",
+			&ImportOpts{ForceValid: true, IgnoreInvalid: true, ReferenceDate: sampleDate})
+		require.NoError(t, err)
+
+		repairs := make(map[string]Repair)
+		for _, issue := range issues {
+			if issue.Code == CodeInconsistentTimezone {
+				repairs[issue.Table+"."+issue.Column] = issue.Repair
+			}
+		}
+		// The agency is left for the user to fix, as deleting it would delete everything it runs
+		assert.Equal(t, map[string]Repair{"agency.agency_timezone": "", "stops.stop_timezone": RepairNullify}, repairs)
+
+		conn, err := sqlite.OpenConn(outDir+"/imported.db", sqlite.SQLITE_OPEN_READONLY)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		// The stop is kept and inherits the timezone of its station
+		var timezones []string
+		err = sqlitex.Exec(conn, "SELECT stop_timezone FROM stops WHERE stop_id = 'BEATTY_AIRPORT'",
+			func(stmt *sqlite.Stmt) error {
+				timezones = append(timezones, stmt.GetText("stop_timezone"))
+				return nil
+			})
+		require.NoError(t, err)
+		assert.Equal(t, []string{""}, timezones)
+
+		var routes []string
+		err = sqlitex.Exec(conn, "SELECT route_id FROM routes WHERE agency_id = 'NYC'", func(stmt *sqlite.Stmt) error {
+			routes = append(routes, stmt.GetText("route_id"))
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"CITY"}, routes)
+	})
+}
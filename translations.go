@@ -0,0 +1,150 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// translatableTables can be referenced by table_name in translations.txt. Their records are referenced by their
+// primary key, with record_sub_id for the second column of stop_times' key.
+var translatableTables = []string{
+	"agency", "stops", "routes", "trips", "stop_times", "pathways", "levels", "feed_info", "attributions",
+}
+
+func quotedTranslatableTables() string {
+	var quoted []string
+	for _, table := range translatableTables {
+		quoted = append(quoted, "'"+table+"'")
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// validateTranslationFields checks table_name and field_name refer to a column that can be translated
+func (v *validator) validateTranslationFields() error {
+	query := "SELECT rowid, * FROM translations WHERE table_name IS NOT NULL AND field_name IS NOT NULL"
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		row := rowValues("translations", stmt)
+		table := row["table_name"]
+		if !slices.Contains(translatableTables, table) {
+			v.reject(ValidationIssue{
+				Code:    CodeInvalidTranslationField,
+				Message: fmt.Sprintf("%s in translations.txt is not a table_name that can be translated", table),
+				Table:   "translations",
+				Column:  "table_name",
+				RowID:   stmt.GetInt64("rowid"),
+				Value:   table,
+				Row:     row,
+			})
+			return nil
+		}
+		if _, ok := gtfsSchema[table].Columns[row["field_name"]]; !ok {
+			v.reject(ValidationIssue{
+				Code:    CodeInvalidTranslationField,
+				Message: fmt.Sprintf("%s in translations.txt is not a field_name of %s.txt", row["field_name"], table),
+				Table:   "translations",
+				Column:  "field_name",
+				RowID:   stmt.GetInt64("rowid"),
+				Value:   row["field_name"],
+				Row:     row,
+			})
+		}
+		return nil
+	})
+}
+
+// validateTranslationRecords checks record_id and record_sub_id match a record of the table translated
+func (v *validator) validateTranslationRecords() error {
+	for _, table := range translatableTables {
+		key := gtfsSchema[table].PrimaryKey
+		if len(key) == 0 {
+			continue
+		}
+
+		var query, description string
+		if len(key) == 1 {
+			query = fmt.Sprintf(`
+SELECT rowid, * FROM translations
+WHERE table_name = ? AND record_id IS NOT NULL AND record_id NOT IN (SELECT %s FROM %s WHERE %s IS NOT NULL)`,
+				key[0], table, key[0])
+			description = fmt.Sprintf("%s.%s", table, key[0])
+		} else {
+			query = fmt.Sprintf(`
+SELECT rowid, * FROM translations
+WHERE table_name = ? AND record_id IS NOT NULL AND NOT EXISTS (
+	SELECT 1 FROM %s WHERE %s = translations.record_id AND %s IS translations.record_sub_id
+)`, table, key[0], key[1])
+			description = fmt.Sprintf("%s.%s and %s", table, key[0], key[1])
+		}
+
+		err := sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+			row := rowValues("translations", stmt)
+			value := row["record_id"]
+			if len(key) > 1 {
+				value += "," + row["record_sub_id"]
+			}
+			v.reject(ValidationIssue{
+				Code:    CodeOrphanedTranslation,
+				Message: fmt.Sprintf("record_id %s in translations.txt doesn't match any %s", value, description),
+				Table:   "translations",
+				Column:  "record_id",
+				RowID:   stmt.GetInt64("rowid"),
+				Value:   value,
+				Row:     row,
+			})
+			return nil
+		}, table)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTranslationValues checks field_value matches a value of the field translated
+func (v *validator) validateTranslationValues() error {
+	type field struct{ table, column string }
+	var fields []field
+	query := fmt.Sprintf(
+		"SELECT DISTINCT table_name, field_name FROM translations WHERE field_value IS NOT NULL AND table_name IN (%s)",
+		quotedTranslatableTables())
+	err := sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		f := field{table: stmt.GetText("table_name"), column: stmt.GetText("field_name")}
+		// Invalid fields are reported by validateTranslationFields
+		if _, ok := gtfsSchema[f.table].Columns[f.column]; ok {
+			fields = append(fields, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		query := fmt.Sprintf(`
+SELECT rowid, * FROM translations
+WHERE table_name = ? AND field_name = ? AND field_value IS NOT NULL
+	AND field_value NOT IN (SELECT %s FROM %s WHERE %s IS NOT NULL)`, f.column, f.table, f.column)
+
+		err := sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+			row := rowValues("translations", stmt)
+			v.reject(ValidationIssue{
+				Code:    CodeOrphanedTranslation,
+				Message: fmt.Sprintf("field_value %s in translations.txt doesn't match any %s.%s", row["field_value"], f.table, f.column),
+				Table:   "translations",
+				Column:  "field_value",
+				RowID:   stmt.GetInt64("rowid"),
+				Value:   row["field_value"],
+				Row:     row,
+			})
+			return nil
+		}, f.table, f.column)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
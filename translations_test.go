@@ -0,0 +1,54 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidateTranslations(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"translations.txt": "table_name,field_name,language,translation,record_id,record_sub_id,field_value\n" +
+			// Valid
+			"stops,stop_name,fr,Hôtel Stagecoach,STAGECOACH,,\n" +
+			"stop_times,stop_headsign,fr,Aéroport,STBA,2,\n" +
+			"stops,stop_name,de,Flughafen,,,Nye County Airport (Demo)\n" +
+			// Invalid
+			"calendar,service_id,fr,Semaine,FULLW,,\n" +
+			"stops,stop_nom,fr,Grenouille,BULLFROG,,\n" +
+			"stops,stop_name,fr,Nulle part,nonexistent_stop,,\n" +
+			"stop_times,stop_headsign,fr,Nulle part,STBA,3,\n" +
+			"routes,route_long_name,fr,Nulle part,,,Nowhere\n",
+	})
+
+	issues, err := Validate(input, &ValidateOpts{ReferenceDate: sampleDate})
+	require.ErrorIs(t, err, ErrInvalidInput)
+	assert.ElementsMatch(t, []string{
+		"calendar in translations.txt is not a table_name that can be translated",
+		"stop_nom in translations.txt is not a field_name of stops.txt",
+		"record_id nonexistent_stop in translations.txt doesn't match any stops.stop_id",
+		"record_id STBA,3 in translations.txt doesn't match any stop_times.trip_id and stop_sequence",
+		"field_value Nowhere in translations.txt doesn't match any routes.route_long_name",
+	}, issueMessages(issues))
+
+	t.Run("fix", func(t *testing.T) {
+		outDir := testTempdir(t)
+		issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ForceValid: true, ReferenceDate: sampleDate})
+		require.NoError(t, err)
+		assert.Len(t, issues, 5)
+
+		conn, err := sqlite.OpenConn(outDir+"/imported.db", sqlite.SQLITE_OPEN_READONLY)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		var remaining []string
+		err = sqlitex.Exec(conn, "SELECT translation FROM translations ORDER BY rowid", func(stmt *sqlite.Stmt) error {
+			remaining = append(remaining, stmt.GetText("translation"))
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Hôtel Stagecoach", "Aéroport", "Flughafen"}, remaining)
+	})
+}
@@ -0,0 +1,263 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // Timezones are validated against the IANA database
+)
+
+// valueKind is how values of a column are stored in SQLite
+type valueKind int
+
+const (
+	textKind valueKind = iota
+	integerKind
+	realKind
+	dateKind // YYYYMMDD stored as an integer
+	timeKind // H:MM:SS stored as integer seconds
+)
+
+func (s columnSchema) kind() valueKind {
+	switch s.TypeDescription {
+	case "Integer", "Non-negative integer", "Positive integer", "Non-zero integer", "Non-null integer", "Enum":
+		return integerKind
+	case "Float", "Non-negative float", "Positive float", "Latitude", "Longitude":
+		return realKind
+	case "Date":
+		return dateKind
+	case "Time":
+		return timeKind
+	default:
+		return textKind
+	}
+}
+
+func (k valueKind) sqlType() string {
+	switch k {
+	case integerKind, dateKind, timeKind:
+		return "INTEGER"
+	case realKind:
+		return "REAL"
+	default:
+		return "TEXT"
+	}
+}
+
+// sqlTypeofs lists the values of the SQL typeof() function a well-formed value of this kind can have
+func (k valueKind) sqlTypeofs() []string {
+	switch k {
+	case integerKind, dateKind, timeKind:
+		return []string{"integer"}
+	case realKind:
+		return []string{"real", "integer"}
+	default:
+		return []string{"text"}
+	}
+}
+
+// parseValue parses the text of a GTFS field into an int64 or float64. If ok is
+// false the value should be stored as text.
+func parseValue(kind valueKind, text string) (value any, ok bool) {
+	switch kind {
+	case integerKind:
+		v, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		return v, true
+	case realKind:
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, false
+		}
+		return v, true
+	case dateKind:
+		if len(text) != 8 {
+			return nil, false
+		}
+		if _, err := time.Parse("20060102", text); err != nil {
+			return nil, false
+		}
+		v, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		return v, true
+	case timeKind:
+		v, err := parseTime(text)
+		if err != nil {
+			return nil, false
+		}
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+// formatValue is the inverse of parseValue
+func formatValue(kind valueKind, value any) string {
+	switch v := value.(type) {
+	case int64:
+		if kind == timeKind {
+			return formatTime(v)
+		}
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		panic(fmt.Sprintf("unexpected value type %T", value))
+	}
+}
+
+// columnValue gets a value of a row in the form parseValue returns
+func columnValue(stmt *sqlite.Stmt, col int) any {
+	switch stmt.ColumnType(col) {
+	case sqlite.SQLITE_INTEGER:
+		return stmt.ColumnInt64(col)
+	case sqlite.SQLITE_FLOAT:
+		return stmt.ColumnFloat(col)
+	case sqlite.SQLITE_NULL:
+		return nil
+	default:
+		return stmt.ColumnText(col)
+	}
+}
+
+// parseTime parses a GTFS time (H:MM:SS or HH:MM:SS, where the hours may exceed 24) into seconds
+func parseTime(text string) (int64, error) {
+	n := len(text)
+	if n < 7 || text[n-3] != ':' || text[n-6] != ':' {
+		return 0, fmt.Errorf("invalid time %q", text)
+	}
+	for i := range n {
+		if i != n-3 && i != n-6 && (text[i] < '0' || text[i] > '9') {
+			return 0, fmt.Errorf("invalid time %q", text)
+		}
+	}
+	h, err := strconv.ParseInt(text[:n-6], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	m, _ := strconv.ParseInt(text[n-5:n-3], 10, 64)
+	s, _ := strconv.ParseInt(text[n-2:], 10, 64)
+	if m >= 60 || s >= 60 {
+		return 0, fmt.Errorf("invalid time %q", text)
+	}
+	return h*3600 + m*60 + s, nil
+}
+
+func formatTime(seconds int64) string {
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// valueChecks validate values by TypeDescription. They are passed values in the form parseValue returns, and
+// only called with values stored as the kind of the column.
+var valueChecks = map[string]func(value any) bool{
+	"Date": func(value any) bool {
+		_, err := time.Parse("20060102", fmt.Sprintf("%08d", value))
+		return err == nil
+	},
+	"Time": func(value any) bool {
+		return value.(int64) >= 0
+	},
+	"Color": func(value any) bool {
+		return colorPattern.MatchString(value.(string))
+	},
+	"Latitude": func(value any) bool {
+		v := toFloat(value)
+		return v >= -90 && v <= 90
+	},
+	"Longitude": func(value any) bool {
+		v := toFloat(value)
+		return v >= -180 && v <= 180
+	},
+	"Timezone": func(value any) bool {
+		return isTimezone(value.(string))
+	},
+	"Language code": func(value any) bool {
+		return languageCodePattern.MatchString(value.(string))
+	},
+	"Currency code": func(value any) bool {
+		return currencyCodePattern.MatchString(value.(string))
+	},
+	"Currency amount": func(value any) bool {
+		return currencyAmountPattern.MatchString(value.(string))
+	},
+	"URL": func(value any) bool {
+		u, err := url.Parse(value.(string))
+		return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+	},
+	"Email": func(value any) bool {
+		addr, err := mail.ParseAddress(value.(string))
+		return err == nil && addr.Name == "" && !strings.HasPrefix(value.(string), "<")
+	},
+	"Positive integer": func(value any) bool {
+		return value.(int64) > 0
+	},
+	"Non-negative integer": func(value any) bool {
+		return value.(int64) >= 0
+	},
+	"Non-zero integer": func(value any) bool {
+		return value.(int64) != 0
+	},
+	"Non-null integer": func(value any) bool {
+		return value.(int64) != 0
+	},
+	"Positive float": func(value any) bool {
+		return toFloat(value) > 0
+	},
+	"Non-negative float": func(value any) bool {
+		return toFloat(value) >= 0
+	},
+}
+
+var (
+	colorPattern          = regexp.MustCompile(`^[0-9A-Fa-f]{6}$`)
+	languageCodePattern   = regexp.MustCompile(`^[A-Za-z]{2,8}(-[A-Za-z0-9]{1,8})*$`) // IETF BCP 47
+	currencyCodePattern   = regexp.MustCompile(`^[A-Z]{3}$`)                          // ISO 4217
+	currencyAmountPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+)
+
+func toFloat(value any) float64 {
+	switch v := value.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		panic(fmt.Sprintf("unexpected value type %T", value))
+	}
+}
+
+var (
+	timezonesMu sync.Mutex
+	timezones   = make(map[string]bool)
+)
+
+func isTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+
+	timezonesMu.Lock()
+	defer timezonesMu.Unlock()
+	valid, ok := timezones[name]
+	if !ok {
+		_, err := time.LoadLocation(name)
+		valid = err == nil
+		timezones[name] = valid
+	}
+	return valid
+}
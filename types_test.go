@@ -0,0 +1,77 @@
+package gtfs2sqlite
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseValue(t *testing.T) {
+	cases := []struct {
+		kind  valueKind
+		text  string
+		value any
+	}{
+		{integerKind, "3", int64(3)},
+		{integerKind, "-1", int64(-1)},
+		{integerKind, "1.5", nil},
+		{realKind, "36.425288", 36.425288},
+		{realKind, "NaN", nil},
+		{dateKind, "20070101", int64(20070101)},
+		{dateKind, "20070230", nil},
+		{dateKind, "2007-01-01", nil},
+		{timeKind, "6:00:00", int64(6 * 3600)},
+		{timeKind, "25:35:00", int64(25*3600 + 35*60)},
+		{timeKind, "6:60:00", nil},
+		{timeKind, "6:0:00", nil},
+		{timeKind, "-6:00:00", nil},
+	}
+	for _, c := range cases {
+		value, ok := parseValue(c.kind, c.text)
+		assert.Equal(t, c.value != nil, ok, c.text)
+		assert.Equal(t, c.value, value, c.text)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	assert.Equal(t, "06:00:00", formatValue(timeKind, int64(6*3600)))
+	assert.Equal(t, "25:35:00", formatValue(timeKind, int64(25*3600+35*60)))
+	assert.Equal(t, "20070101", formatValue(dateKind, int64(20070101)))
+	assert.Equal(t, "36.425288", formatValue(realKind, 36.425288))
+	assert.Equal(t, "", formatValue(realKind, nil))
+}
+
+func TestValueChecks(t *testing.T) {
+	cases := []struct {
+		typeDescription string
+		value           any
+		valid           bool
+	}{
+		{"Date", int64(20240229), true},
+		{"Date", int64(20230229), false},
+		{"Color", "FFFFFF", true},
+		{"Color", "#FFFFFF", false},
+		{"Latitude", 36.4, true},
+		{"Latitude", -90.1, false},
+		{"Longitude", 180.0, true},
+		{"Longitude", 181.0, false},
+		{"Timezone", "America/Los_Angeles", true},
+		{"Timezone", "America/Los Angeles", false},
+		{"Timezone", "Local", false},
+		{"Language code", "en", true},
+		{"Language code", "zh-Hant-TW", true},
+		{"Language code", "english (uk)", false},
+		{"Currency code", "USD", true},
+		{"Currency code", "$", false},
+		{"URL", "http://google.com", true},
+		{"URL", "google.com", false},
+		{"Email", "info@example.com", true},
+		{"Email", "Info <info@example.com>", false},
+		{"Email", "info", false},
+		{"Positive integer", int64(0), false},
+		{"Non-negative integer", int64(0), true},
+		{"Non-negative float", -0.5, false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.valid, valueChecks[c.typeDescription](c.value), "%s %v", c.typeDescription, c.value)
+	}
+}
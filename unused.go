@@ -0,0 +1,109 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// unusedEntity describes the entities in Table that nothing references. Entities spread over several rows, like
+// shapes, are reported once.
+type unusedEntity struct {
+	Table string
+	// Column identifies the entity
+	Column string
+	// When is an SQL expression over rows of Table that is true for unused entities
+	When string
+	// Reason describes When, completing "is unused as"
+	Reason string
+}
+
+const (
+	// servedStops are stops stop_times.txt serves, directly or as part of a location group
+	servedStops = "SELECT stop_id FROM stop_times WHERE stop_id IS NOT NULL " +
+		"UNION SELECT stop_id FROM location_group_stops " +
+		"WHERE stop_id IS NOT NULL AND location_group_id IN (SELECT location_group_id FROM stop_times)"
+	// usedStops are served stops, the stations they are part of, and the entrances, nodes and boarding areas of both
+	usedStops = "WITH served AS (" + servedStops + "), " +
+		"stations AS (SELECT stop_id FROM served UNION SELECT parent_station FROM stops " +
+		"WHERE parent_station IS NOT NULL AND stop_id IN (SELECT stop_id FROM served)) " +
+		"SELECT stop_id FROM stations UNION SELECT stop_id FROM stops WHERE parent_station IN (SELECT stop_id FROM stations)"
+	usedServices = "SELECT service_id FROM trips UNION SELECT service_id FROM timeframe " +
+		"UNION SELECT prior_notice_service_id FROM booking_rules"
+)
+
+var unusedEntities = []unusedEntity{
+	{Table: "agency", Column: "agency_id",
+		When: "NOT EXISTS (SELECT 1 FROM routes WHERE routes.agency_id = agency.agency_id " +
+			"OR (routes.agency_id IS NULL AND (SELECT count(*) FROM agency) = 1))",
+		Reason: "no routes belong to it"},
+	{Table: "stops", Column: "stop_id",
+		When:   "stop_id NOT IN (" + usedStops + ")",
+		Reason: "no stop times serve it or a stop in the same station"},
+	{Table: "routes", Column: "route_id",
+		When:   "route_id NOT IN (SELECT route_id FROM trips WHERE route_id IS NOT NULL)",
+		Reason: "no trips run on it"},
+	{Table: "shapes", Column: "shape_id",
+		When:   "shape_id NOT IN (SELECT shape_id FROM trips WHERE shape_id IS NOT NULL)",
+		Reason: "no trips follow it"},
+	{Table: "calendar", Column: "service_id",
+		When:   "service_id NOT IN (SELECT service_id FROM (" + usedServices + ") WHERE service_id IS NOT NULL)",
+		Reason: "no trips, timeframes or booking rules use it"},
+	// Services in both calendar.txt and calendar_dates.txt are reported once
+	{Table: "calendar_dates", Column: "service_id",
+		When: "service_id NOT IN (SELECT service_id FROM (" + usedServices + ") WHERE service_id IS NOT NULL) " +
+			"AND service_id NOT IN (SELECT service_id FROM calendar WHERE service_id IS NOT NULL)",
+		Reason: "no trips, timeframes or booking rules use it"},
+	{Table: "levels", Column: "level_id",
+		When:   "level_id NOT IN (SELECT level_id FROM stops WHERE level_id IS NOT NULL)",
+		Reason: "no stops are on it"},
+	{Table: "fare_attributes", Column: "fare_id",
+		When:   "fare_id NOT IN (SELECT fare_id FROM fare_rules WHERE fare_id IS NOT NULL)",
+		Reason: "no fare rules apply it"},
+}
+
+// unusedCondition is the When expression of the unusedEntity for table. Clip deletes the rows it matches after
+// removing trips outside the clip feature, so what Clip keeps is what isn't warned about.
+func unusedCondition(table string) string {
+	for _, entity := range unusedEntities {
+		if entity.Table == table {
+			return entity.When
+		}
+	}
+	panic("no unusedEntity for " + table)
+}
+
+func (v *validator) validateUnused(entity unusedEntity) error {
+	query := fmt.Sprintf(
+		"SELECT min(rowid) AS rowid, group_concat(rowid) AS __gtfs2sqlite_rowids, * FROM %s WHERE %s GROUP BY %s",
+		entity.Table, entity.When, entity.Column)
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		row := rowValues(entity.Table, stmt)
+		issue := ValidationIssue{
+			Code:    CodeUnusedEntity,
+			Message: fmt.Sprintf("%s in %s.txt is unused as %s", row[entity.Column], entity.Table, entity.Reason),
+			Table:   entity.Table,
+			Column:  entity.Column,
+			RowID:   stmt.GetInt64("rowid"),
+			Value:   row[entity.Column],
+			Row:     row,
+		}
+		// Only deleting applies, and it removes every row of the entity
+		issue.Repair = v.repairFor(issue)
+		if issue.Repair == RepairDelete {
+			issue.Deleted = true
+			for _, rowid := range strings.Split(stmt.GetText("__gtfs2sqlite_rowids"), ",") {
+				rowid, err := strconv.ParseInt(rowid, 10, 64)
+				if err != nil {
+					return err
+				}
+				v.remove(entity.Table, rowid, issue)
+			}
+		}
+		v.report(issue)
+		return nil
+	})
+}
@@ -0,0 +1,106 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidateUnused(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"agency.txt": "agency_id,agency_name,agency_url,agency_timezone\n" +
+			"DTA,Demo Transit Authority,http://google.com,America/Los_Angeles\n" +
+			"UNUSED,Unused Transit Authority,http://google.com,America/Los_Angeles\n",
+		"routes.txt": "route_id,agency_id,route_short_name,route_long_name,route_type\n" +
+			"AB,DTA,10,Airport - Bullfrog,3\n" +
+			"BFC,DTA,20,Bullfrog - Furnace Creek Resort,3\n" +
+			"STBA,DTA,30,Stagecoach - Airport Shuttle,3\n" +
+			"CITY,DTA,40,City,3\n" +
+			"AAMV,DTA,50,Airport - Amargosa Valley,3\n" +
+			"UNUSED,UNUSED,60,Nowhere,3\n",
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station,level_id\n" +
+			"FUR_CREEK_RES,Furnace Creek Resort (Demo),36.425288,-117.133162,,,\n" +
+			"BEATTY_AIRPORT,Nye County Airport (Demo),36.868446,-116.784582,,BEATTY_AIRPORT_STATION,\n" +
+			"BEATTY_AIRPORT_STATION,Nye County Airport Station (Demo),36.868446,-116.784582,1,,\n" +
+			"BEATTY_AIRPORT_ENTRANCE,Nye County Airport Entrance (Demo),36.868446,-116.784582,2,BEATTY_AIRPORT_STATION,L1\n" +
+			"BULLFROG,Bullfrog (Demo),36.88108,-116.81797,,,\n" +
+			"STAGECOACH,Stagecoach Hotel & Casino (Demo),36.915682,-116.751677,,,\n" +
+			"NADAV,North Ave / D Ave N (Demo),36.914893,-116.76821,,,\n" +
+			"NANAA,North Ave / N A Ave (Demo),36.914944,-116.761472,,,\n" +
+			"DADAN,Doing Ave / D Ave N (Demo),36.909489,-116.768242,,,\n" +
+			"EMSI,E Main St / S Irving St (Demo),36.905697,-116.76218,,,\n" +
+			"AMV,Amargosa Valley (Demo),36.641496,-116.40094,,,\n" +
+			"UNUSED,Nowhere (Demo),36.641496,-116.40094,,,\n",
+		"levels.txt": "level_id,level_index\n" +
+			"L1,0\n" +
+			"UNUSED,1\n",
+		"shapes.txt": "shape_id,shape_pt_lat,shape_pt_lon,shape_pt_sequence\n" +
+			"UNUSED,36.641496,-116.40094,1\n" +
+			"UNUSED,36.868446,-116.784582,2\n",
+		"calendar_dates.txt": "service_id,date,exception_type\n" +
+			"FULLW,20070604,2\n" +
+			"UNUSED,20070604,1\n",
+		"fare_attributes.txt": "fare_id,price,currency_type,payment_method,transfers\n" +
+			"p,1.25,USD,0,0\n" +
+			"a,5.25,USD,0,0\n" +
+			"UNUSED,1,USD,0,0\n",
+	})
+
+	unused := func(issues []ValidationIssue) []string {
+		var out []string
+		for _, issue := range issues {
+			if issue.Code == CodeUnusedEntity {
+				out = append(out, issue.Message)
+			}
+		}
+		return out
+	}
+
+	// The fares don't say which agency they belong to, which isn't what this test is about
+	disabled := []string{CodeConditionallyRequired + ":fare_attributes.agency_id"}
+	issues, err := Validate(input, &ValidateOpts{Rules: &Rules{Disabled: disabled}})
+	require.NoError(t, err)
+	// The agency is used by the unused route
+	assert.ElementsMatch(t, []string{
+		"UNUSED in routes.txt is unused as no trips run on it",
+		"UNUSED in stops.txt is unused as no stop times serve it or a stop in the same station",
+		"UNUSED in levels.txt is unused as no stops are on it",
+		"UNUSED in shapes.txt is unused as no trips follow it",
+		"UNUSED in calendar_dates.txt is unused as no trips, timeframes or booking rules use it",
+		"UNUSED in fare_attributes.txt is unused as no fare rules apply it",
+	}, unused(issues))
+
+	t.Run("fix", func(t *testing.T) {
+		// Unused entities are only deleted if they are made errors
+		rules := &Rules{Disabled: disabled, Severities: map[string]Severity{CodeUnusedEntity: SeverityError}}
+		outDir := testTempdir(t)
+		issues, err := Import(input, outDir+"/imported.db", &ImportOpts{ForceValid: true, Rules: rules})
+		require.NoError(t, err)
+		// Including the agency, once its route has been deleted
+		assert.Len(t, unused(issues), 7)
+
+		conn, err := sqlite.OpenConn(outDir+"/imported.db", sqlite.SQLITE_OPEN_READONLY)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		for _, table := range []string{"agency", "routes", "stops", "levels", "calendar_dates", "fare_attributes"} {
+			var count int64
+			err = sqlitex.Exec(conn, "SELECT count(*) FROM "+table+" WHERE "+gtfsSchema[table].PrimaryKey[0]+" = 'UNUSED'",
+				func(stmt *sqlite.Stmt) error {
+					count = stmt.ColumnInt64(0)
+					return nil
+				})
+			require.NoError(t, err)
+			assert.Zero(t, count, table)
+		}
+		var shapePoints int64
+		err = sqlitex.Exec(conn, "SELECT count(*) FROM shapes", func(stmt *sqlite.Stmt) error {
+			shapePoints = stmt.ColumnInt64(0)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Zero(t, shapePoints)
+	})
+}
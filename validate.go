@@ -1,87 +1,563 @@
 package gtfs2sqlite
 
 import (
+	"archive/zip"
 	"context"
 	"crawshaw.io/sqlite"
 	"crawshaw.io/sqlite/sqlitex"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var ErrInvalidInput = errors.New("invalid input")
 
+type ValidateOpts struct {
+	Rules *Rules
+	// DryRunForceValid finds the rows ForceValid would delete, including rows that would only be deleted because
+	// rows they reference were, without modifying the input
+	DryRunForceValid bool
+	// CoerceInvalidEnums is the same as ImportOpts.CoerceInvalidEnums, for DryRunForceValid
+	CoerceInvalidEnums bool
+	// ReferenceDate is the date the feed should be in effect on. If zero today is used.
+	ReferenceDate time.Time
+}
+
+// Validate runs all checks on a GTFS zip or a database created by Import without modifying it. Databases may have
+// been edited after import. Returns ErrInvalidInput along with the issues if any errors are found.
+func Validate(inputPath string, opts *ValidateOpts) ([]ValidationIssue, error) {
+	if opts == nil {
+		opts = &ValidateOpts{}
+	}
+	issues, _, err := validateFile(inputPath, *opts)
+	return issues, err
+}
+
+// DryRunForceValid is Validate with ValidateOpts.DryRunForceValid set, also returning every row ForceValid would
+// delete in the order it would delete them
+func DryRunForceValid(inputPath string, opts *ValidateOpts) ([]ValidationIssue, []RemovedRow, error) {
+	if opts == nil {
+		opts = &ValidateOpts{}
+	}
+	dryRunOpts := *opts
+	dryRunOpts.DryRunForceValid = true
+	return validateFile(inputPath, dryRunOpts)
+}
+
+func validateFile(inputPath string, opts ValidateOpts) ([]ValidationIssue, []RemovedRow, error) {
+	if inputPath == "" {
+		panic("Missing inputPath")
+	}
+
+	isZip, err := isZipFile(inputPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	slog.Info(fmt.Sprintf("Validating %s", inputPath))
+
+	var db *sqlite.Conn
+	if isZip {
+		inputZip, err := zip.OpenReader(inputPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer func() { _ = inputZip.Close() }()
+
+		tempDir, err := os.MkdirTemp("", "gtfs2sqlite-validate-")
+		if err != nil {
+			return nil, nil, err
+		}
+		defer func() { _ = os.RemoveAll(tempDir) }()
+
+		db, err = sqlite.OpenConn(filepath.Join(tempDir, "validate.db"), 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer func() { _ = db.Close() }()
+
+		if _, err := importInto(db, inputZip, false); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		// A dry run makes changes it rolls back
+		flags := sqlite.SQLITE_OPEN_READONLY
+		if opts.DryRunForceValid {
+			flags = sqlite.SQLITE_OPEN_READWRITE
+		}
+		input, err := sqlite.OpenConn(inputPath, flags)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer func() { _ = input.Close() }()
+		db = input
+
+		legacy, err := legacyTables(db)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(legacy) > 0 {
+			// Migrated in a copy so the input isn't changed
+			tempDir, err := os.MkdirTemp("", "gtfs2sqlite-validate-")
+			if err != nil {
+				return nil, nil, err
+			}
+			defer func() { _ = os.RemoveAll(tempDir) }()
+
+			migrated, err := input.BackupToDB("", filepath.Join(tempDir, "validate.db"))
+			if err != nil {
+				return nil, nil, err
+			}
+			defer func() { _ = migrated.Close() }()
+			db = migrated
+
+			if err := migrateLegacyTables(db, legacy); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	referenceDate := opts.ReferenceDate
+	if referenceDate.IsZero() {
+		referenceDate = time.Now()
+	}
+	return runValidation(db, validateOpts{
+		force:         opts.DryRunForceValid,
+		coerceEnums:   opts.CoerceInvalidEnums,
+		dryRun:        opts.DryRunForceValid,
+		rules:         opts.Rules,
+		logLevel:      slog.LevelError,
+		referenceDate: referenceDate,
+	})
+}
+
+// isZipFile checks for the signature at the start of a zip file, as opposed to the one at the start of a SQLite
+// database
+func isZipFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = f.Close() }()
+
+	signature := make([]byte, 4)
+	if _, err := io.ReadFull(f, signature); err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, err
+	}
+	return string(signature) == "PK\x03\x04", nil
+}
+
 type validateOpts struct {
-	force    bool
-	ignore   bool
-	logLevel slog.Level
+	force       bool
+	coerceEnums bool
+	ignore      bool
+	dryRun      bool // force in a savepoint that is rolled back
+	rules       *Rules
+	logLevel    slog.Level // for errors
+	// referenceDate is the date the feed should be in effect on. If zero the feed's dates aren't checked.
+	referenceDate time.Time
 }
 
-func validate(db *sqlite.Conn, opts validateOpts) ([]string, error) {
-	v := &validator{db: db, opts: opts, toDelete: make(map[string][]int64)}
+func validate(db *sqlite.Conn, opts validateOpts) ([]ValidationIssue, error) {
+	issues, _, err := runValidation(db, opts)
+	return issues, err
+}
+
+// runValidation validates db, also returning the rows deleted in a dry run
+func runValidation(db *sqlite.Conn, opts validateOpts) ([]ValidationIssue, []RemovedRow, error) {
+	v := &validator{db: db, opts: opts, toDelete: make(map[string][]removal), placeholders: make(map[placeholder]bool)}
 
 	slog.Info("Validating")
 
+	fileColumns, err := readFileColumns(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	v.fileColumns = fileColumns
+
+	var removed []RemovedRow
+	if opts.dryRun {
+		if err := sqlitex.ExecTransient(db, "SAVEPOINT __gtfs2sqlite_dry_run", sqlitexNoop); err != nil {
+			return nil, nil, err
+		}
+		// Rows already in __gtfs2sqlite_removed were deleted by an earlier import
+		var previouslyRemoved int64
+		previouslyRemoved, err = lastRemovedID(db)
+		if err == nil {
+			err = v.run()
+		}
+		if err == nil {
+			removed, err = readRemoved(db, "id > ?", previouslyRemoved)
+		}
+		rollbackErr := sqlitex.ExecTransient(db, "ROLLBACK TO __gtfs2sqlite_dry_run", sqlitexNoop)
+		if rollbackErr == nil {
+			rollbackErr = sqlitex.ExecTransient(db, "RELEASE __gtfs2sqlite_dry_run", sqlitexNoop)
+		}
+		err = errors.Join(err, rollbackErr)
+	} else {
+		err = v.run()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hasErrors := slices.ContainsFunc(v.issues, func(issue ValidationIssue) bool {
+		return issue.Severity == SeverityError
+	})
+	hasUnrepaired := slices.ContainsFunc(v.issues, func(issue ValidationIssue) bool {
+		return issue.Severity == SeverityError && issue.Repair == ""
+	})
+	// A dry run doesn't fix anything
+	if hasErrors && (!opts.force || opts.dryRun || hasUnrepaired) && !opts.ignore {
+		return v.issues, removed, ErrInvalidInput
+	}
+	return v.issues, removed, nil
+}
+
+type validator struct {
+	db          *sqlite.Conn
+	opts        validateOpts
+	fileColumns map[string][]string // table -> columns in the header of the file, nil if unknown
+	issues      []ValidationIssue
+	pass        int
+	toDelete    map[string][]removal // by table
+	toUpdate    []update
+	toCreate    []placeholder
+	// placeholders have been created, so shouldn't be created again if they were invalid and had to be repaired
+	placeholders map[placeholder]bool
+}
+
+type removal struct {
+	rowid int64
+	issue ValidationIssue // the issue that caused the row to be removed
+}
+
+// readFileColumns reads the headers of the files recorded on import. Databases created by older versions
+// don't have them.
+func readFileColumns(db *sqlite.Conn) (map[string][]string, error) {
+	var exists bool
+	err := sqlitex.Exec(db, "SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = '__gtfs2sqlite_file_columns'",
+		func(stmt *sqlite.Stmt) error {
+			exists = true
+			return nil
+		})
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	fileColumns := make(map[string][]string)
+	err = sqlitex.Exec(db, "SELECT tableName, columnName FROM __gtfs2sqlite_file_columns", func(stmt *sqlite.Stmt) error {
+		table := stmt.GetText("tableName")
+		fileColumns[table] = append(fileColumns[table], stmt.GetText("columnName"))
+		return nil
+	})
+	return fileColumns, err
+}
+
+// report records an issue. Later passes only record the issues they repair, such as rows deleted because rows they
+// reference were, as any others were already found by an earlier pass.
+func (v *validator) report(issue ValidationIssue) {
+	issue.Severity = v.opts.rules.severity(issue.Code, issue.Table, issue.Column)
+	if issue.Severity == "" || (v.pass != 0 && issue.Repair == "") {
+		return
+	}
+	issue.Pass = v.pass
+
+	level := v.opts.logLevel
+	if issue.Severity == SeverityWarning {
+		level = slog.LevelWarn
+	}
+	slog.Log(context.Background(), level, issue.String(), "code", issue.Code)
+
+	v.issues = append(v.issues, issue)
+}
+
+// reject reports an issue with a row, repairing it if forcing valid
+func (v *validator) reject(issue ValidationIssue) {
+	issue.Repair = v.repairFor(issue)
+	switch issue.Repair {
+	case RepairDelete:
+		issue.Deleted = true
+		v.remove(issue.Table, issue.RowID, issue)
+	case RepairNullify:
+		v.toUpdate = append(v.toUpdate, update{table: issue.Table, column: issue.Column, rowid: issue.RowID})
+	case RepairDefault:
+		value := *gtfsSchema[issue.Table].Columns[issue.Column].Enum.Default
+		v.toUpdate = append(v.toUpdate, update{table: issue.Table, column: issue.Column, rowid: issue.RowID, value: value})
+	case RepairPlaceholder:
+		v.toCreate = append(v.toCreate, placeholderFor(issue))
+	case RepairRewrite:
+		v.toUpdate = append(v.toUpdate, update{table: issue.Table, column: issue.Column, rowid: issue.RowID, value: issue.Suggestions[0]})
+	}
+	if v.pass > 0 && issue.Repair != "" {
+		v.supersede(issue)
+	}
+	v.report(issue)
+}
+
+// supersede updates the issues found on earlier passes with the same value as issue to show how it was finally
+// repaired. An earlier repair can fail, such as when a rewritten ID references a row that was deleted, or a
+// nullified column turns out to be required.
+func (v *validator) supersede(issue ValidationIssue) {
+	for i, earlier := range v.issues {
+		if earlier.Pass < v.pass && earlier.Table == issue.Table && earlier.Column == issue.Column &&
+			earlier.RowID == issue.RowID {
+			v.issues[i].Repair = issue.Repair
+			v.issues[i].Deleted = issue.Deleted
+		}
+	}
+}
+
+func (v *validator) remove(table string, rowid int64, issue ValidationIssue) {
+	v.toDelete[table] = append(v.toDelete[table], removal{rowid: rowid, issue: issue})
+}
+
+// fixes is whether issues should be fixed by ForceValid. Only errors are fixed.
+func (v *validator) fixes(code, table, column string) bool {
+	return v.opts.force && v.opts.rules.severity(code, table, column) == SeverityError
+}
+
+// run validates every table until there is nothing left to fix. Fixes are made in a single transaction, and after
+// the first pass only tables that were changed or that depend on a changed table are validated again.
+func (v *validator) run() (err error) {
+	defer sqlitex.Save(v.db)(&err)
+
+	var changed map[string]bool // tables changed by the previous pass, nil on the first
 	for {
 		for table, schema := range gtfsSchema {
+			if changed != nil && !affectedBy(table, changed) {
+				continue
+			}
 			if err := v.validateTable(table, schema); err != nil {
-				return nil, err
+				return err
 			}
 		}
-		if len(v.toDelete) == 0 {
-			break
+		if len(v.toDelete) == 0 && len(v.toUpdate) == 0 && len(v.toCreate) == 0 {
+			return nil
+		}
+		changed = make(map[string]bool)
+		if err := sqlitex.ExecScript(v.db, originalTextSchema); err != nil {
+			return err
 		}
 
+		for _, u := range v.toUpdate {
+			query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE rowid = ?", u.table, u.column)
+			if err := sqlitex.Exec(v.db, query, sqlitexNoop, u.value, u.rowid); err != nil {
+				return err
+			}
+			if err := forgetOriginalText(v.db, u.table, u.rowid, u.column); err != nil {
+				return err
+			}
+			changed[u.table] = true
+		}
+		if len(v.toUpdate) > 0 {
+			slog.Info(fmt.Sprintf("Repaired %d value(s)", len(v.toUpdate)))
+		}
+		v.toUpdate = nil
+
+		created := 0
+		for _, p := range v.toCreate {
+			v.placeholders[p] = true
+			ok, err := createPlaceholder(v.db, p)
+			if err != nil {
+				return err
+			}
+			if ok {
+				changed[p.table] = true
+				created++
+			}
+		}
+		if created > 0 {
+			slog.Info(fmt.Sprintf("Created %d placeholder(s)", created))
+		}
+		v.toCreate = nil
+
 		deleted := 0
-		for table, rows := range v.toDelete {
-			query := fmt.Sprintf("DELETE FROM %s WHERE rowid = ?", table)
-			for _, rowid := range rows {
-				if err := sqlitex.Exec(db, query, sqlitexNoop, rowid); err != nil {
-					return nil, err
-				}
-				deleted++
+		for table, removals := range v.toDelete {
+			n, err := removeRows(v.db, table, removals, v.pass)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				changed[table] = true
 			}
+			deleted += n
 		}
 		slog.Info(fmt.Sprintf("Re-validating after force deleting %d row(s)", deleted))
-		v.toDelete = make(map[string][]int64)
+		v.toDelete = make(map[string][]removal)
 		v.pass++
 	}
-
-	if len(v.issues) > 0 {
-		if opts.force || opts.ignore {
-			return v.issues, nil
-		} else {
-			return v.issues, ErrInvalidInput
-		}
-	}
-	return nil, nil
 }
 
-type validator struct {
-	db       *sqlite.Conn
-	opts     validateOpts
-	issues   []string
-	pass     int
-	toDelete map[string][]int64 // table -> rowid
+// affectedBy is whether the issues in table could have changed since changed tables were last validated
+func affectedBy(table string, changed map[string]bool) bool {
+	return changed[table] || slices.ContainsFunc(tableDependencies[table], func(dependency string) bool {
+		return changed[dependency]
+	})
 }
 
-func (v *validator) append(msg string, args ...any) {
-	issue := fmt.Sprintf(msg, args...)
-	slog.Log(context.Background(), v.opts.logLevel, issue)
-	v.issues = append(v.issues, issue)
+// tableDependencies maps each table to the other tables its checks read: the tables its foreign IDs reference, any
+// tables queried by its presence rules or unusedEntities and those read by its tableChecks. A table can depend on
+// itself.
+var tableDependencies = findTableDependencies()
+
+var sqlTableReference = regexp.MustCompile(`(?i)\b(?:FROM|JOIN)\s+(\w+)`)
+
+func findTableDependencies() map[string][]string {
+	dependencies := make(map[string][]string)
+	add := func(table, dependency string) {
+		if !slices.Contains(dependencies[table], dependency) {
+			dependencies[table] = append(dependencies[table], dependency)
+		}
+	}
+
+	for table, schema := range gtfsSchema {
+		for _, column := range schema.Columns {
+			if column.ForeignID == nil {
+				continue
+			}
+			if column.ForeignID.Table != "" {
+				add(table, column.ForeignID.Table)
+			}
+			for _, ref := range column.ForeignID.AnyOf {
+				add(table, ref.Table)
+			}
+		}
+	}
+	for _, check := range tableChecks {
+		for _, dependency := range check.Reads {
+			add(check.Table, dependency)
+		}
+	}
+	addQueried := func(table, expression string) {
+		for _, match := range sqlTableReference.FindAllStringSubmatch(expression, -1) {
+			// Skip common table expressions
+			if _, ok := gtfsSchema[match[1]]; ok {
+				add(table, match[1])
+			}
+		}
+	}
+	for _, rule := range presenceRules {
+		addQueried(rule.Table, rule.When)
+	}
+	for _, entity := range unusedEntities {
+		addQueried(entity.Table, entity.When)
+	}
+	return dependencies
 }
 
 func (v *validator) validateTable(table string, schema tableSchema) error {
+	if len(schema.PrimaryKey) > 0 {
+		if err := v.validatePrimaryKey(table, schema); err != nil {
+			return err
+		}
+	}
 	for column, schema := range schema.Columns {
 		if err := v.validateColumn(table, column, schema); err != nil {
 			return err
 		}
 	}
+	for _, rule := range presenceRules {
+		if rule.Table == table {
+			if err := v.validatePresenceRule(rule); err != nil {
+				return err
+			}
+		}
+	}
+	for _, check := range tableChecks {
+		if check.Table == table {
+			if err := check.Check(v); err != nil {
+				return err
+			}
+		}
+	}
+	for _, entity := range unusedEntities {
+		if entity.Table == table {
+			if err := v.validateUnused(entity); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// validatePrimaryKey checks for rows with the same primary key. Unlike a unique index, NULLs are considered equal
+// to each other.
+func (v *validator) validatePrimaryKey(table string, schema tableSchema) error {
+	columns := strings.Join(schema.PrimaryKey, ", ")
+	query := fmt.Sprintf(
+		"SELECT %s, count(*) AS count, min(rowid) AS first, group_concat(rowid) AS rowids FROM %s GROUP BY %s HAVING count(*) > 1",
+		columns, table, columns)
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		key := make(map[string]string)
+		var keyValues []string
+		for i, column := range schema.PrimaryKey {
+			value := formatValue(schema.Columns[column].kind(), columnValue(stmt, i))
+			if value != "" {
+				key[column] = value
+			}
+			keyValues = append(keyValues, value)
+		}
+
+		// Keep the first occurrence
+		first := stmt.GetInt64("first")
+		var duplicates []int64
+		for _, rowid := range strings.Split(stmt.GetText("rowids"), ",") {
+			rowid, err := strconv.ParseInt(rowid, 10, 64)
+			if err != nil {
+				return err
+			}
+			if rowid != first {
+				duplicates = append(duplicates, rowid)
+			}
+		}
+		slices.Sort(duplicates)
+
+		issue := ValidationIssue{
+			Code:    CodeDuplicatePrimaryKey,
+			Message: fmt.Sprintf("%s is duplicated %d times in %s.txt", formatRow(key), stmt.GetInt64("count"), table),
+			Table:   table,
+			Column:  strings.Join(schema.PrimaryKey, ","),
+			RowID:   duplicates[0],
+			Value:   strings.Join(keyValues, ","),
+			Row:     key,
+		}
+		if v.fixes(issue.Code, issue.Table, issue.Column) {
+			issue.Repair = RepairDelete
+			issue.Deleted = true
+			for _, rowid := range duplicates {
+				v.remove(table, rowid, issue)
+			}
+		}
+		v.report(issue)
+
+		return nil
+	})
+}
+
 func (v *validator) validateColumn(table, column string, schema columnSchema) error {
+	if schema.PresenceDescription == "Required" {
+		if err := v.validateRequired(table, column, schema); err != nil {
+			return err
+		}
+	}
+	if err := v.validateValues(table, column, schema); err != nil {
+		return err
+	}
+	if schema.Enum != nil {
+		if err := v.validateEnum(table, column, *schema.Enum); err != nil {
+			return err
+		}
+	}
 	if schema.ForeignID != nil {
 		if err := v.validateForeignID(table, column, *schema.ForeignID); err != nil {
 			return err
@@ -90,6 +566,169 @@ func (v *validator) validateColumn(table, column string, schema columnSchema) er
 	return nil
 }
 
+func (v *validator) validateRequired(table, column string, schema columnSchema) error {
+	columnMissing := false
+	missingColumnIssue := ValidationIssue{
+		Code:    CodeMissingRequiredColumn,
+		Message: fmt.Sprintf("%s.txt is missing required column %s", table, column),
+		Table:   table,
+		Column:  column,
+	}
+	header, ok := v.fileColumns[table]
+	if ok && !slices.Contains(header, column) && v.opts.rules.severity(CodeMissingRequiredColumn, table, column) != "" {
+		columnMissing = true
+		if v.fixes(CodeMissingRequiredColumn, table, column) {
+			missingColumnIssue.Repair = RepairDelete
+			missingColumnIssue.Deleted = true
+		}
+		// Later passes would report it again though its rows were deleted on the first
+		if v.pass == 0 {
+			v.report(missingColumnIssue)
+		}
+	}
+	if schema.EmptyAllowed && !columnMissing {
+		return nil
+	}
+
+	query := fmt.Sprintf("SELECT rowid, * FROM %s WHERE %s IS NULL", table, column)
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		// If the whole column is missing we've already reported it
+		if columnMissing {
+			if v.fixes(CodeMissingRequiredColumn, table, column) {
+				v.remove(table, stmt.GetInt64("rowid"), missingColumnIssue)
+			}
+			return nil
+		}
+
+		v.reject(ValidationIssue{
+			Code:    CodeMissingRequiredValue,
+			Message: fmt.Sprintf("%s.txt is missing required %s", table, column),
+			Table:   table,
+			Column:  column,
+			RowID:   stmt.GetInt64("rowid"),
+			Row:     rowValues(table, stmt),
+		})
+		return nil
+	})
+}
+
+func (v *validator) validatePresenceRule(rule presenceRule) error {
+	var query string
+	if rule.Forbidden {
+		query = fmt.Sprintf("SELECT rowid, * FROM %s WHERE %s IS NOT NULL AND (%s)", rule.Table, rule.Column, rule.When)
+	} else {
+		query = fmt.Sprintf("SELECT rowid, * FROM %s WHERE %s IS NULL AND (%s)", rule.Table, rule.Column, rule.When)
+	}
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		issue := ValidationIssue{
+			Table:  rule.Table,
+			Column: rule.Column,
+			RowID:  stmt.GetInt64("rowid"),
+			Row:    rowValues(rule.Table, stmt),
+		}
+		if rule.Forbidden {
+			issue.Code = CodeConditionallyForbidden
+			issue.Message = fmt.Sprintf("%s.txt has %s, which is forbidden when %s", rule.Table, rule.Column, rule.Reason)
+			issue.Value = issue.Row[rule.Column]
+		} else {
+			issue.Code = CodeConditionallyRequired
+			issue.Message = fmt.Sprintf("%s.txt is missing %s, which is required when %s", rule.Table, rule.Column, rule.Reason)
+		}
+		v.reject(issue)
+		return nil
+	})
+}
+
+// validateValues checks values were parsed into the storage class of the column on import and are valid for
+// the TypeDescription.
+func (v *validator) validateValues(table, column string, schema columnSchema) error {
+	kind := schema.kind()
+	check := valueChecks[schema.TypeDescription]
+	// Enums that aren't integers are reported by validateEnum, so they can be coerced to the default
+	if (kind == textKind && check == nil) || schema.Enum != nil {
+		return nil
+	}
+
+	typeofs := kind.sqlTypeofs()
+	quotedTypeofs := make([]string, len(typeofs))
+	for i := range typeofs {
+		quotedTypeofs[i] = "'" + typeofs[i] + "'"
+	}
+	query := fmt.Sprintf("SELECT rowid, *, typeof(%s) AS __gtfs2sqlite_typeof FROM %s WHERE %s IS NOT NULL",
+		column, table, column)
+	if check == nil {
+		query += fmt.Sprintf(" AND typeof(%s) NOT IN (%s)", column, strings.Join(quotedTypeofs, ", "))
+	}
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		value := columnValue(stmt, stmt.ColumnIndex(column))
+
+		valid := slices.Contains(typeofs, stmt.GetText("__gtfs2sqlite_typeof"))
+		if valid && check != nil {
+			valid = check(value)
+		}
+		if valid {
+			return nil
+		}
+
+		text := formatValue(kind, value)
+		v.reject(ValidationIssue{
+			Code:    CodeInvalidValue,
+			Message: fmt.Sprintf("%s in %s.txt is not a valid %s (expected %s)", text, table, column, schema.TypeDescription),
+			Table:   table,
+			Column:  column,
+			RowID:   stmt.GetInt64("rowid"),
+			Value:   text,
+			Row:     rowValues(table, stmt),
+		})
+		return nil
+	})
+}
+
+func (v *validator) validateEnum(table, column string, schema enumSchema) error {
+	var values []string
+	for _, value := range schema.Values {
+		values = append(values, strconv.FormatInt(value, 10))
+	}
+	query := fmt.Sprintf("SELECT rowid, * FROM %s WHERE %s IS NOT NULL AND (typeof(%s) != 'integer' OR %s NOT IN (%s))",
+		table, column, column, column, strings.Join(values, ", "))
+
+	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
+		value := stmt.GetText(column)
+		v.reject(ValidationIssue{
+			Code:    CodeInvalidEnum,
+			Message: fmt.Sprintf("%s in %s.txt is not a valid %s (expected one of %s)", value, table, column, formatEnumValues(schema.Values)),
+			Table:   table,
+			Column:  column,
+			RowID:   stmt.GetInt64("rowid"),
+			Value:   value,
+			Row:     rowValues(table, stmt),
+		})
+		return nil
+	})
+}
+
+// formatEnumValues formats values compactly, writing runs as ranges
+func formatEnumValues(values []int64) string {
+	var out []string
+	for i := 0; i < len(values); {
+		j := i
+		for j+1 < len(values) && values[j+1] == values[j]+1 {
+			j++
+		}
+		if j-i >= 2 {
+			out = append(out, fmt.Sprintf("%d-%d", values[i], values[j]))
+		} else {
+			for k := i; k <= j; k++ {
+				out = append(out, strconv.FormatInt(values[k], 10))
+			}
+		}
+		i = j + 1
+	}
+	return strings.Join(out, ", ")
+}
+
 func (v *validator) validateForeignID(table, column string, schema foreignIDSchema) error {
 	// Normalize to AnyOf form
 	if len(schema.AnyOf) > 0 {
@@ -102,9 +741,10 @@ func (v *validator) validateForeignID(table, column string, schema foreignIDSche
 		schema.Column = ""
 	}
 
+	// A NULL in the foreign table would make NOT IN never match
 	var foreignFragments []string
 	for _, subSchema := range schema.AnyOf {
-		fragment := fmt.Sprintf("SELECT %s FROM %s", subSchema.Column, subSchema.Table)
+		fragment := fmt.Sprintf("SELECT %s FROM %s WHERE %s IS NOT NULL", subSchema.Column, subSchema.Table, subSchema.Column)
 		foreignFragments = append(foreignFragments, fragment)
 	}
 	foreignFragment := strings.Join(foreignFragments, " UNION ")
@@ -112,30 +752,50 @@ func (v *validator) validateForeignID(table, column string, schema foreignIDSche
 	query := fmt.Sprintf("SELECT rowid, * FROM %s WHERE %s IS NOT NULL AND %s NOT IN (%s)",
 		table, column, column, foreignFragment)
 
+	suggester := newIDSuggester(v.db, foreignFragment)
 	return sqlitex.Exec(v.db, query, func(stmt *sqlite.Stmt) error {
-		rowid := stmt.GetInt64("rowid")
 		value := stmt.GetText(column)
-
-		if v.pass == 0 {
-			v.append("%s in %s.txt is not a valid %s [%s]", value, table, column, prettyPrintRow(stmt))
-		}
-
-		if v.opts.force {
-			v.toDelete[table] = append(v.toDelete[table], rowid)
+		suggestions, err := suggester.suggest(value)
+		if err != nil {
+			return err
 		}
-
+		v.reject(ValidationIssue{
+			Code:        CodeInvalidForeignID,
+			Message:     fmt.Sprintf("%s in %s.txt is not a valid %s%s", value, table, column, formatSuggestions(suggestions)),
+			Table:       table,
+			Column:      column,
+			RowID:       stmt.GetInt64("rowid"),
+			Value:       value,
+			Row:         rowValues(table, stmt),
+			Suggestions: suggestions,
+		})
 		return nil
 	})
 }
 
-func prettyPrintRow(row *sqlite.Stmt) string {
-	var out []string
+// rowValues formats the non-empty fields of a row
+func rowValues(table string, row *sqlite.Stmt) map[string]string {
+	out := make(map[string]string)
 	for i := range row.ColumnCount() {
 		column := row.ColumnName(i)
-		value := row.GetText(column)
-		if column != "rowid" && value != "" {
-			out = append(out, fmt.Sprintf("%s: %s", column, value))
+		if column == "rowid" || strings.HasPrefix(column, "__gtfs2sqlite") {
+			continue
+		}
+		value := formatValue(gtfsSchema[table].Columns[column].kind(), columnValue(row, i))
+		if value != "" {
+			out[column] = value
 		}
 	}
-	return strings.Join(out, ", ")
+	return out
+}
+
+// readRow reads the non-empty fields of the row of table with rowid, for checks that only keep the fields they need
+// until they find an issue
+func (v *validator) readRow(table string, rowid int64) (map[string]string, error) {
+	var row map[string]string
+	err := sqlitex.Exec(v.db, fmt.Sprintf("SELECT * FROM %s WHERE rowid = ?", table), func(stmt *sqlite.Stmt) error {
+		row = rowValues(table, stmt)
+		return nil
+	}, rowid)
+	return row, err
 }
@@ -0,0 +1,210 @@
+package gtfs2sqlite
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidateZip(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		issues, err := Validate("./sample_data/sample-feed.zip", &ValidateOpts{ReferenceDate: sampleDate})
+		require.NoError(t, err)
+		assert.Empty(t, issues)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		issues, err := Validate("./sample_data/invalid-foreign-key.zip", &ValidateOpts{ReferenceDate: sampleDate})
+		require.ErrorIs(t, err, ErrInvalidInput)
+		require.Len(t, issues, 1)
+		assert.Equal(t, CodeInvalidForeignID, issues[0].Code)
+	})
+}
+
+func TestValidateDatabase(t *testing.T) {
+	outDir := testTempdir(t)
+	_, err := Import("./sample_data/sample-feed.zip", outDir+"/feed.db", &ImportOpts{ReferenceDate: sampleDate})
+	require.NoError(t, err)
+
+	issues, err := Validate(outDir+"/feed.db", &ValidateOpts{ReferenceDate: sampleDate})
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	// Edit by hand
+	conn, err := sqlite.OpenConn(outDir+"/feed.db", 0)
+	require.NoError(t, err)
+	err = sqlitex.Exec(conn, "UPDATE trips SET route_id = 'NONEXISTENT' WHERE trip_id = 'AB1'", sqlitexNoop)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	issues, err = Validate(outDir+"/feed.db", &ValidateOpts{ReferenceDate: sampleDate})
+	require.ErrorIs(t, err, ErrInvalidInput)
+	require.Len(t, issues, 1)
+	assert.Equal(t, "NONEXISTENT in trips.txt is not a valid route_id", issues[0].Message)
+}
+
+func TestValidateDryRunForceValid(t *testing.T) {
+	outDir := testTempdir(t)
+	_, err := Import("./sample_data/invalid-foreign-key.zip", outDir+"/feed.db", &ImportOpts{IgnoreInvalid: true, ReferenceDate: sampleDate})
+	require.NoError(t, err)
+
+	for _, input := range []string{"./sample_data/invalid-foreign-key.zip", outDir + "/feed.db"} {
+		t.Run(input, func(t *testing.T) {
+			issues, err := Validate(input, &ValidateOpts{
+				DryRunForceValid: true, Rules: deleteInvalidForeignIDs, ReferenceDate: sampleDate,
+			})
+			require.ErrorIs(t, err, ErrInvalidInput)
+
+			deleted := make(map[string]int)
+			passes := make(map[string]int)
+			for _, issue := range issues {
+				assert.True(t, issue.Deleted)
+				deleted[issue.Table]++
+				passes[issue.Table] = issue.Pass
+			}
+			// Deleting the route cascades to its trips, their stop times and its fare rules
+			assert.Equal(t, map[string]int{"routes": 1, "trips": 2, "stop_times": 4, "fare_rules": 1}, deleted)
+			assert.Equal(t, map[string]int{"routes": 0, "trips": 1, "stop_times": 2, "fare_rules": 1}, passes)
+		})
+	}
+
+	// Nothing was deleted
+	issues, err := Validate(outDir+"/feed.db", &ValidateOpts{ReferenceDate: sampleDate})
+	require.ErrorIs(t, err, ErrInvalidInput)
+	require.Len(t, issues, 1)
+}
+
+func TestDryRunForceValidRemovedRows(t *testing.T) {
+	input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+		"levels.txt": "level_id,level_name\n" +
+			"L1,Ground\n" +
+			"L2,Mezzanine\n",
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon\n" +
+			"FUR_CREEK_RES,Furnace Creek Resort (Demo),36.425288,-117.133162\n" +
+			"FUR_CREEK_RES,Furnace Creek Resort Again (Demo),36.425288,-117.133162\n" +
+			"FUR_CREEK_RES,Furnace Creek Resort Again (Demo),36.425288,-117.133162\n" +
+			"BEATTY_AIRPORT,Nye County Airport (Demo),36.868446,-116.784582\n" +
+			"BULLFROG,Bullfrog (Demo),36.88108,-116.81797\n" +
+			"STAGECOACH,Stagecoach Hotel & Casino (Demo),36.915682,-116.751677\n" +
+			"NADAV,North Ave / D Ave N (Demo),36.914893,-116.76821\n" +
+			"NANAA,North Ave / N A Ave (Demo),36.914944,-116.761472\n" +
+			"DADAN,Doing Ave / D Ave N (Demo),36.909489,-116.768242\n" +
+			"EMSI,E Main St / S Irving St (Demo),36.905697,-116.76218\n" +
+			"AMV,Amargosa Valley (Demo),36.641496,-116.40094\n",
+	})
+
+	issues, removed, err := DryRunForceValid(input, &ValidateOpts{ReferenceDate: sampleDate})
+	require.ErrorIs(t, err, ErrInvalidInput)
+
+	var codes []string
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			codes = append(codes, issue.Code)
+		}
+	}
+	// The missing column isn't reported again after its rows were deleted
+	assert.ElementsMatch(t, []string{CodeDuplicatePrimaryKey, CodeMissingRequiredColumn}, codes)
+
+	// Every row of an issue is listed
+	var rows []string
+	for _, row := range removed {
+		assert.Zero(t, row.Pass)
+		rows = append(rows, fmt.Sprintf("%s %d %s", row.Table, row.RowID, formatRow(row.Row)))
+	}
+	assert.ElementsMatch(t, []string{
+		"levels 1 level_id: L1, level_name: Ground",
+		"levels 2 level_id: L2, level_name: Mezzanine",
+		"stops 2 stop_id: FUR_CREEK_RES, stop_lat: 36.425288, stop_lon: -117.133162, stop_name: Furnace Creek Resort Again (Demo)",
+		"stops 3 stop_id: FUR_CREEK_RES, stop_lat: 36.425288, stop_lon: -117.133162, stop_name: Furnace Creek Resort Again (Demo)",
+	}, rows)
+}
+
+func TestDryRunMatchesForceValid(t *testing.T) {
+	input := testFeed(t, "./sample_data/invalid-foreign-key.zip", map[string]string{
+		"frequencies.txt": "trip_id,start_time,end_time,headway_secs,exact_times\n" +
+			"STBA,6:00:00,22:00:00,1800,2\n",
+	})
+	for _, rules := range []*Rules{nil, deleteInvalidForeignIDs} {
+		opts := &ValidateOpts{Rules: rules, CoerceInvalidEnums: true, ReferenceDate: sampleDate}
+		dryRunIssues, dryRunRemoved, err := DryRunForceValid(input, opts)
+		require.ErrorIs(t, err, ErrInvalidInput)
+
+		outDir := testTempdir(t)
+		issues, err := Import(input, outDir+"/feed.db", &ImportOpts{
+			ForceValid: true, Rules: rules, CoerceInvalidEnums: true, ReferenceDate: sampleDate,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, sortedIssues(issues), sortedIssues(dryRunIssues))
+
+		conn, err := sqlite.OpenConn(outDir+"/feed.db", 0)
+		require.NoError(t, err)
+		// Only created if something was removed
+		require.NoError(t, sqlitex.ExecTransient(conn, removedSchema, sqlitexNoop))
+		removed, err := readRemoved(conn, "1")
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+		assert.ElementsMatch(t, removed, dryRunRemoved)
+	}
+}
+
+func TestTableDependencies(t *testing.T) {
+	assert.ElementsMatch(t, []string{"stop_times", "trips", "stops", "location_groups", "booking_rules", "routes"},
+		tableDependencies["stop_times"])
+	// From the presence rules
+	assert.Contains(t, tableDependencies["routes"], "route_networks")
+	assert.Contains(t, tableDependencies["agency"], "agency")
+
+	assert.True(t, affectedBy("stop_times", map[string]bool{"trips": true}))
+	assert.False(t, affectedBy("stop_times", map[string]bool{"fare_rules": true}))
+	assert.True(t, affectedBy("fare_rules", map[string]bool{"fare_rules": true}))
+}
+
+func TestValidateServiceIDs(t *testing.T) {
+	bookingRules := "booking_rule_id,booking_type,prior_notice_last_day,prior_notice_last_time,prior_notice_service_id\n" +
+		"CALENDAR,2,1,17:00:00,WE\n" +
+		"CALENDAR_DATES,2,1,17:00:00,HOLIDAY\n" +
+		"MISSING,2,1,17:00:00,nonexistent_service\n"
+	calendarDates := "service_id,date,exception_type\n" +
+		"FULLW,20070604,2\n" +
+		"HOLIDAY,20071225,1\n"
+
+	invalid := func(t *testing.T, input string) []string {
+		issues, err := Validate(input, &ValidateOpts{ReferenceDate: sampleDate})
+		require.ErrorIs(t, err, ErrInvalidInput)
+		var out []string
+		for _, issue := range issues {
+			if issue.Code == CodeInvalidForeignID {
+				out = append(out, issue.Table+" "+issue.Value)
+			}
+		}
+		return out
+	}
+
+	t.Run("both", func(t *testing.T) {
+		input := testFeed(t, "./sample_data/sample-feed.zip", map[string]string{
+			"booking_rules.txt": bookingRules,
+			// A missing service_id doesn't hide invalid references
+			"calendar_dates.txt": calendarDates + ",20071226,1\n",
+		})
+		assert.Equal(t, []string{"booking_rules nonexistent_service"}, invalid(t, input))
+	})
+	t.Run("no calendar.txt", func(t *testing.T) {
+		input := testFeed(t, testFeedWithout(t, "./sample_data/sample-feed.zip", "calendar.txt"), map[string]string{
+			"booking_rules.txt":  bookingRules,
+			"calendar_dates.txt": calendarDates,
+		})
+		// The trips running on weekends and booking rule CALENDAR reference WE
+		assert.ElementsMatch(t, []string{
+			"booking_rules WE", "booking_rules nonexistent_service",
+			"trips WE", "trips WE", "trips WE", "trips WE",
+		}, invalid(t, input))
+	})
+	t.Run("no calendar_dates.txt", func(t *testing.T) {
+		input := testFeed(t, testFeedWithout(t, "./sample_data/sample-feed.zip", "calendar_dates.txt"), map[string]string{
+			"booking_rules.txt": bookingRules,
+		})
+		assert.ElementsMatch(t, []string{"booking_rules HOLIDAY", "booking_rules nonexistent_service"}, invalid(t, input))
+	})
+}